@@ -0,0 +1,64 @@
+package gitstatus
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+var benchRepoSizes = []struct {
+	name   string
+	nfiles int
+}{
+	{"small", 10},
+	{"medium", 1000},
+	{"huge", 20000},
+}
+
+// BenchmarkNew measures New, using the git executable, in synthetic
+// repositories of various sizes.
+func BenchmarkNew(b *testing.B) {
+	for _, size := range benchRepoSizes {
+		if testing.Short() && size.nfiles > 1000 {
+			continue
+		}
+
+		dir := b.TempDir()
+		synthRepo(b, dir, size.nfiles)
+
+		b.Run(size.name, func(b *testing.B) {
+			chdir(b, dir)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := New(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkPorcelainParse measures the parsing of git status output with
+// various numbers of entries.
+func BenchmarkPorcelainParse(b *testing.B) {
+	for _, size := range benchRepoSizes {
+		lines := []string{"## main...origin/main [ahead 1, behind 2]"}
+		xy := []string{" M", "M ", "MM", "A ", "??", "UU", "R "}
+		for i := 0; i < size.nfiles; i++ {
+			lines = append(lines, fmt.Sprintf("%s dir%03d/file%05d", xy[i%len(xy)], i/100, i))
+		}
+		out := porcelainNZT(lines...)
+
+		b.Run(size.name, func(b *testing.B) {
+			b.SetBytes(int64(len(out)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				var p Porcelain
+				if err := p.parseFrom(bytes.NewReader(out)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package gitstatus
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Branch represents a local branch and its divergence with its upstream
+// branch.
+type Branch struct {
+	// Name is the name of the local branch.
+	Name string
+
+	// RemoteBranch is the name of upstream remote branch (tracking), empty if
+	// the local branch doesn't track any branch.
+	RemoteBranch string
+
+	// UpstreamGone reports whether the upstream remote branch is configured
+	// but doesn't exist anymore, in which case AheadCount and BehindCount
+	// are 0.
+	UpstreamGone bool
+
+	// AheadCount reports by how many commits the local branch is ahead of its upstream branch.
+	AheadCount int
+
+	// BehindCount reports by how many commits the local branch is behind its upstream branch.
+	BehindCount int
+}
+
+// Branches returns all local branches of the repository of the current
+// working directory, along with their divergence with their upstream branch.
+// Options that don't apply to listing branches are ignored.
+func Branches(opts ...Option) ([]Branch, error) {
+	return listBranches(context.Background(), opts)
+}
+
+// BranchesWithContext is like Branches but includes a context.
+//
+// The provided context is used to stop listing branches if the context becomes
+// done before the call to git has completed.
+func BranchesWithContext(ctx context.Context, opts ...Option) ([]Branch, error) {
+	return listBranches(ctx, opts)
+}
+
+func listBranches(ctx context.Context, opts []Option) ([]Branch, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if err := o.checkRepository(); err != nil {
+		return nil, err
+	}
+
+	var branches branchList
+	err := o.runAndParse(ctx, &branches, "git", "for-each-ref",
+		"--format=%(refname:short)%00%(upstream:short)%00%(upstream:track)",
+		"refs/heads")
+	if err != nil {
+		return nil, err
+	}
+	return branches, nil
+}
+
+type branchList []Branch
+
+// parseFrom parses the output of git for-each-ref, one branch per line, each
+// line made of the nil-separated local branch, upstream and tracking info.
+func (bl *branchList) parseFrom(r io.Reader) error {
+	scan, release := newScanner(r, bufio.ScanLines)
+	defer release()
+
+	for scan.Scan() {
+		fields := strings.Split(scan.Text(), "\x00")
+		if len(fields) != 3 {
+			return fmt.Errorf("unexpected branch format %q", scan.Text())
+		}
+
+		b := Branch{Name: fields[0], RemoteBranch: fields[1]}
+		switch track := fields[2]; track {
+		case "":
+		case "[gone]":
+			b.UpstreamGone = true
+		default:
+			var err error
+			b.AheadCount, b.BehindCount, err = parseAheadBehind(track)
+			if err != nil {
+				return err
+			}
+		}
+		*bl = append(*bl, b)
+	}
+
+	return scan.Err()
+}
@@ -0,0 +1,80 @@
+package gitstatus
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBranchListParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		out     string // git for-each-ref output
+		want    branchList
+		wantErr bool
+	}{
+		{
+			name: "no upstream",
+			out:  "main\x00\x00\n",
+			want: branchList{{Name: "main"}},
+		},
+		{
+			name: "all cases",
+			out: "main\x00origin/main\x00\n" +
+				"feature/123/a\x00upstream/feature/123/a\x00[ahead 26, behind 2]\n" +
+				"ahead\x00origin/ahead\x00[ahead 3]\n" +
+				"behind\x00origin/behind\x00[behind 1]\n" +
+				"gone\x00origin/gone\x00[gone]\n",
+			want: branchList{
+				{Name: "main", RemoteBranch: "origin/main"},
+				{Name: "feature/123/a", RemoteBranch: "upstream/feature/123/a", AheadCount: 26, BehindCount: 2},
+				{Name: "ahead", RemoteBranch: "origin/ahead", AheadCount: 3},
+				{Name: "behind", RemoteBranch: "origin/behind", BehindCount: 1},
+				{Name: "gone", RemoteBranch: "origin/gone", UpstreamGone: true},
+			},
+		},
+		{
+			name:    "missing fields",
+			out:     "main\n",
+			wantErr: true,
+		},
+		{
+			name:    "illformed track",
+			out:     "main\x00origin/main\x00[ahead]\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got branchList
+			err := got.parseFrom(strings.NewReader(tt.out))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestBranchesOptions(t *testing.T) {
+	dir := t.TempDir()
+	synthRepo(t, dir, 1)
+
+	// Options apply, regardless of the current directory.
+	var dc DebugCapture
+	branches, err := Branches(WithDir(dir), WithDebugCapture(&dc))
+	require.NoError(t, err)
+	require.Len(t, branches, 1)
+	assert.NotEmpty(t, dc.Commands())
+
+	notRepo := t.TempDir()
+	_, err = Branches(WithDir(notRepo), WithCeilingDirectories(filepath.Dir(notRepo)))
+	assert.Truef(t, errors.Is(err, ErrNotARepository), "got error %v, want %v", err, ErrNotARepository)
+}
@@ -1,30 +1,27 @@
 package gitstatus
 
 import (
+	"bufio"
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
 	"strings"
+	"sync"
+	"time"
 )
 
-var env []string
-
-type parserFrom interface {
-	parseFrom(r io.Reader) error
-}
-
-func runAndParse(ctx context.Context, p parserFrom, prog string, args ...string) error {
-	select {
-	case <-ctx.Done():
-		return ctx.Err()
-	default:
-	}
+var (
+	envOnce sync.Once
+	env     []string
+)
 
-	if env == nil {
-		// cache env
+// gitEnv returns the environment of the git child processes.
+func gitEnv() []string {
+	envOnce.Do(func() {
 		env = []string{
 			"LC_ALL=C",             // override any user-specific localization
 			"GIT_OPTIONAL_LOCKS=0", // disable operations requiring locks
@@ -34,20 +31,260 @@ func runAndParse(ctx context.Context, p parserFrom, prog string, args ...string)
 		if ok {
 			env = append(env, "HOME="+home)
 		}
+	})
+	return env
+}
+
+// noNetworkEnv are the environment variables added to that of git processes
+// when using WithNoNetwork.
+var noNetworkEnv = []string{
+	"GIT_TERMINAL_PROMPT=0", // fail rather than prompting for credentials
+	// Don't fetch missing objects in partial clones (git 2.45 and later).
+	"GIT_NO_LAZY_FETCH=1",
+	// Disable credential helpers (git 2.31 and later).
+	"GIT_CONFIG_COUNT=1",
+	"GIT_CONFIG_KEY_0=credential.helper",
+	"GIT_CONFIG_VALUE_0=",
+}
+
+// fetchPassEnv are the variables of the environment passed to git fetch, as
+// it may need them to reach and authenticate to remotes.
+var fetchPassEnv = []string{
+	"PATH", "SSH_AUTH_SOCK",
+	"GIT_SSH", "GIT_SSH_COMMAND", "GIT_SSH_VARIANT",
+	"XDG_CONFIG_HOME",
+	"http_proxy", "https_proxy", "all_proxy", "no_proxy",
+	"HTTP_PROXY", "HTTPS_PROXY", "ALL_PROXY", "NO_PROXY",
+	"SystemRoot", "USERPROFILE", "APPDATA", "LOCALAPPDATA",
+}
+
+// fetchEnv returns the environment variables added to that of git fetch, so
+// that it can authenticate to remotes, but fails rather than waiting for user
+// input.
+func fetchEnv() []string {
+	var environ []string
+	for _, name := range fetchPassEnv {
+		if v, ok := os.LookupEnv(name); ok {
+			environ = append(environ, name+"="+v)
+		}
+	}
+	environ = append(environ,
+		"GIT_TERMINAL_PROMPT=0", // don't prompt for credentials
+		"GCM_INTERACTIVE=never", // nor let Git Credential Manager do it
+	)
+
+	// Don't let ssh prompt for passwords or host keys either, unless the user
+	// chose the ssh command.
+	_, ssh := os.LookupEnv("GIT_SSH")
+	_, sshCommand := os.LookupEnv("GIT_SSH_COMMAND")
+	if !ssh && !sshCommand {
+		environ = append(environ, "GIT_SSH_COMMAND=ssh -o BatchMode=yes")
+	}
+	return environ
+}
+
+// env returns the environment of the git child processes run with o.
+func (o *options) env() []string {
+	environ := gitEnv()
+	environ = environ[:len(environ):len(environ)]
+	if o.noNetwork {
+		environ = append(environ, noNetworkEnv...)
+	}
+	if o.network {
+		environ = append(environ, fetchEnv()...)
+	}
+	if o.indexFile != "" {
+		environ = append(environ, "GIT_INDEX_FILE="+o.indexFile)
+	}
+	return environ
+}
+
+type parserFrom interface {
+	parseFrom(r io.Reader) error
+}
+
+// truncatableParser is implemented by parsers of outputs which can be limited
+// with WithOutputLimit.
+type truncatableParser interface {
+	parserFrom
+
+	// parseTruncatedFrom parses r, an output truncated after its first bytes.
+	parseTruncatedFrom(r io.Reader) error
+}
+
+// limitWriter writes at most n bytes to w, silently discarding the others.
+// If keepHeader is set, the output is always written up to its first NUL
+// byte, which ends the header of git status -z, even beyond n.
+type limitWriter struct {
+	w          io.Writer
+	n          int
+	keepHeader bool
+	truncated  bool
+}
+
+func (lw *limitWriter) Write(p []byte) (int, error) {
+	if lw.keepHeader {
+		end := len(p)
+		if i := bytes.IndexByte(p, 0); i >= 0 {
+			end = i + 1
+			lw.keepHeader = false
+		}
+		n, err := lw.w.Write(p[:end])
+		lw.n -= n
+		if lw.n < 0 {
+			lw.n = 0
+		}
+		if err != nil || end == len(p) {
+			return n, err
+		}
+		m, err := lw.Write(p[end:])
+		return n + m, err
 	}
+
+	if len(p) > lw.n {
+		lw.truncated = true
+		if _, err := lw.w.Write(p[:lw.n]); err != nil {
+			return 0, err
+		}
+		lw.n = 0
+		// Let the command write its whole output.
+		return len(p), nil
+	}
+	n, err := lw.w.Write(p)
+	lw.n -= n
+	return n, err
+}
+
+// maxPooledBufSize is the capacity above which buffers are not returned to
+// their pool, so that a single huge output doesn't stay in memory forever.
+const maxPooledBufSize = 4 << 20
+
+// outPool and errPool are pools of *bytes.Buffer receiving the standard and
+// error outputs of git processes.
+var outPool, errPool = sync.Pool{New: newBuffer}, sync.Pool{New: newBuffer}
+
+func newBuffer() any { return new(bytes.Buffer) }
+
+func getBuffer(pool *sync.Pool) *bytes.Buffer {
+	buf := pool.Get().(*bytes.Buffer)
+	buf.Reset()
+	return buf
+}
+
+func putBuffer(pool *sync.Pool, buf *bytes.Buffer) {
+	if buf.Cap() <= maxPooledBufSize {
+		pool.Put(buf)
+	}
+}
+
+// scanBufPool is a pool of *[]byte used as initial buffers of bufio.Scanner.
+var scanBufPool = sync.Pool{
+	New: func() any {
+		b := make([]byte, 4096)
+		return &b
+	},
+}
+
+// newScanner returns a bufio.Scanner reading r and splitting tokens with split.
+// Its initial buffer is taken from a pool, to which it's returned when
+// release is called. The scanner must not be used after release.
+func newScanner(r io.Reader, split bufio.SplitFunc) (scan *bufio.Scanner, release func()) {
+	buf := scanBufPool.Get().(*[]byte)
+	scan = bufio.NewScanner(r)
+	scan.Buffer(*buf, bufio.MaxScanTokenSize)
+	scan.Split(split)
+	return scan, func() { scanBufPool.Put(buf) }
+}
+
+// runAndParse runs prog with args and parses its standard output with p.
+func (o *options) runAndParse(ctx context.Context, p parserFrom, prog string, args ...string) (err error) {
+	if err := ctxErr(ctx); err != nil {
+		return err
+	}
+	if o.replay != nil {
+		return o.replay.run(ctx, p, prog, args...)
+	}
+
+	stdout, stderr := getBuffer(&outPool), getBuffer(&errPool)
+	defer putBuffer(&outPool, stdout)
+	defer putBuffer(&errPool, stderr)
+
 	// parse porcelain status
 	cmd := exec.CommandContext(ctx, prog, args...)
-	cmd.Env = env
+	cmd.Env = o.env()
+	cmd.Dir = o.dir
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	tp, truncatable := p.(truncatableParser)
+	var limited *limitWriter
+	if truncatable && o.outputLimit > 0 {
+		limited = &limitWriter{w: stdout, n: o.outputLimit, keepHeader: true}
+		cmd.Stdout = limited
+	}
 
-	buf, err := cmd.Output()
+	if o.capture != nil {
+		start := time.Now()
+		defer func() {
+			o.capture.add(CapturedCommand{
+				Args:      append([]string{prog}, args...),
+				Duration:  time.Since(start),
+				ExitCode:  cmd.ProcessState.ExitCode(),
+				Stdout:    stdout.String(),
+				Stderr:    stderr.String(),
+				Truncated: limited != nil && limited.truncated,
+				Err:       errString(err),
+			})
+		}()
+	}
+
+	if o.lowPriority {
+		err = startLowPriority(cmd)
+	} else {
+		err = cmd.Start()
+	}
+	if err == nil {
+		err = cmd.Wait()
+	}
 	if err != nil {
-		return fmt.Errorf("exec %s '%v': %w", cmd.Path, strings.Join(args, " "), err)
+		// Mimic exec.Cmd.Output, which reports stderr in exec.ExitError.
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			exitErr.Stderr = append([]byte(nil), stderr.Bytes()...)
+		}
+		err = fmt.Errorf("exec %s '%v': %w", cmd.Path, strings.Join(args, " "), err)
+		if code := runErrorCode(ctx, err, stderr.Bytes()); code != "" {
+			err = withCode(code, err)
+		}
+		return err
 	}
 
-	rbuf := bytes.NewReader(buf)
-	if err := p.parseFrom(rbuf); err != nil {
-		return fmt.Errorf("exec %s '%v': %w", cmd.Path, strings.Join(args, " "), err)
+	rbuf := bytes.NewReader(stdout.Bytes())
+	if limited != nil && limited.truncated {
+		err = tp.parseTruncatedFrom(rbuf)
+	} else {
+		err = p.parseFrom(rbuf)
+	}
+	if err != nil {
+		return withCode(CodeParseError, fmt.Errorf("exec %s '%v': %w", cmd.Path, strings.Join(args, " "), err))
 	}
 
 	return nil
 }
+
+// isExitError reports whether err is caused by a command which exited with a
+// non-zero exit code.
+func isExitError(err error) bool {
+	var (
+		exitErr   *exec.ExitError
+		replayErr *replayExitError
+	)
+	return errors.As(err, &exitErr) || errors.As(err, &replayErr)
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
@@ -0,0 +1,121 @@
+package gitstatus
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestConcurrentFirstUse checks, when run with -race, that concurrent calls to
+// the package API are safe, including those that are the first to setup the
+// shared state (environment of git processes, git version).
+func TestConcurrentFirstUse(t *testing.T) {
+	dir := t.TempDir()
+	synthRepo(t, dir, 10)
+
+	chdir(t, dir)
+
+	// Forget about any previously cached state.
+	envOnce = sync.Once{}
+	env = nil
+	gitVersion, gitVersionErr = nil, nil
+
+	const n = 20
+
+	var wg sync.WaitGroup
+	wg.Add(3 * n)
+	for i := 0; i < n; i++ {
+		go func() {
+			defer wg.Done()
+			if _, err := New(); err != nil {
+				t.Error(err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if _, err := GitVersion(); err != nil {
+				t.Error(err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if _, err := Branches(); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestNoNetworkEnv(t *testing.T) {
+	dir := t.TempDir()
+	synthRepo(t, dir, 1)
+	chdir(t, dir)
+
+	ctx := context.Background()
+	o := options{}
+	require.NoError(t, o.runAndParse(ctx, new(lines), "git", "config", "credential.helper", "store"))
+
+	var helper lines
+	require.NoError(t, o.runAndParse(ctx, &helper, "git", "config", "--get", "credential.helper"))
+	assert.Equal(t, lines{"store"}, helper)
+
+	// WithNoNetwork disables credential helpers.
+	WithNoNetwork()(&o)
+	env := o.env()
+	for _, kv := range []string{
+		"GIT_TERMINAL_PROMPT=0",
+		"GIT_NO_LAZY_FETCH=1",
+		"GIT_CONFIG_COUNT=1",
+		"GIT_CONFIG_KEY_0=credential.helper",
+		"GIT_CONFIG_VALUE_0=",
+	} {
+		assert.Contains(t, env, kv)
+	}
+
+	helper = nil
+	require.NoError(t, o.runAndParse(ctx, &helper, "git", "config", "--get", "credential.helper"))
+	assert.Equal(t, lines{""}, helper)
+
+	// The shared environment is left untouched.
+	assert.NotContains(t, gitEnv(), "GIT_TERMINAL_PROMPT=0")
+}
+
+func TestLimitWriter(t *testing.T) {
+	var buf bytes.Buffer
+	lw := &limitWriter{w: &buf, n: 5}
+
+	n, err := lw.Write([]byte("abc"))
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+	assert.False(t, lw.truncated)
+
+	n, err = lw.Write([]byte("defgh"))
+	require.NoError(t, err)
+	assert.Equal(t, 5, n)
+	assert.True(t, lw.truncated)
+
+	n, err = lw.Write([]byte("ijk"))
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+	assert.Equal(t, "abcde", buf.String())
+
+	// The header is kept whole, even beyond the limit.
+	buf.Reset()
+	lw = &limitWriter{w: &buf, n: 5, keepHeader: true}
+
+	n, err = lw.Write([]byte("## main"))
+	require.NoError(t, err)
+	assert.Equal(t, 7, n)
+	assert.False(t, lw.truncated)
+
+	n, err = lw.Write([]byte("...origin/main\x00?? a\x00"))
+	require.NoError(t, err)
+	assert.Equal(t, 20, n)
+	assert.True(t, lw.truncated)
+	assert.Equal(t, "## main...origin/main\x00", buf.String())
+}
@@ -0,0 +1,69 @@
+package gitstatus
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// DebugCapture records the git commands executed to compute a Status, along
+// with their raw outputs. It's meant to help diagnose parsing issues.
+//
+// A DebugCapture can safely be shared by concurrent calls to New.
+type DebugCapture struct {
+	mu       sync.Mutex
+	commands []CapturedCommand
+}
+
+// CapturedCommand holds information about the execution of a git command.
+type CapturedCommand struct {
+	// Args is the command line, starting with the program name.
+	Args []string
+
+	// Duration is the time it took to run the command.
+	Duration time.Duration
+
+	// ExitCode is the exit code of the command, or -1 if the process didn't
+	// exit normally or couldn't be started.
+	ExitCode int
+
+	// Stdout and Stderr are the raw standard and error outputs.
+	Stdout string
+	Stderr string
+
+	// Truncated reports whether Stdout has been truncated, as with
+	// WithOutputLimit.
+	Truncated bool `json:",omitempty"`
+
+	// Err is the error message if the command failed or its output couldn't
+	// be parsed, empty otherwise.
+	Err string
+}
+
+// WithDebugCapture records in dc every git command executed to compute the
+// Status, including those executed by the lazy mode methods of Status.
+//
+// Commands are recorded even if New fails, which is when they're the most
+// useful.
+func WithDebugCapture(dc *DebugCapture) Option {
+	return func(o *options) { o.capture = dc }
+}
+
+// Commands returns a copy of the commands recorded so far, in execution
+// order.
+func (dc *DebugCapture) Commands() []CapturedCommand {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	return append([]CapturedCommand(nil), dc.commands...)
+}
+
+// MarshalJSON returns the JSON encoding of the recorded commands.
+func (dc *DebugCapture) MarshalJSON() ([]byte, error) {
+	return json.Marshal(dc.Commands())
+}
+
+func (dc *DebugCapture) add(cmd CapturedCommand) {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	dc.commands = append(dc.commands, cmd)
+}
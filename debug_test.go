@@ -0,0 +1,73 @@
+package gitstatus
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDebugCapture(t *testing.T) {
+	dir := t.TempDir()
+	synthRepo(t, dir, 10)
+	chdir(t, dir)
+
+	var dc DebugCapture
+	st, err := New(WithDebugCapture(&dc), WithLazy())
+	require.NoError(t, err)
+
+	cmds := dc.Commands()
+	require.NotEmpty(t, cmds)
+
+	var status *CapturedCommand
+	for i := range cmds {
+		if len(cmds[i].Args) > 1 && cmds[i].Args[1] == "status" {
+			status = &cmds[i]
+		}
+	}
+	require.NotNil(t, status, "git status has not been captured")
+	assert.Equal(t, "git", status.Args[0])
+	assert.Zero(t, status.ExitCode)
+	assert.Empty(t, status.Err)
+	assert.True(t, strings.HasPrefix(status.Stdout, "## "), "unexpected git status output %q", status.Stdout)
+
+	// Commands run by lazy mode methods are captured too.
+	_, _, err = st.DiffStats(context.Background())
+	require.NoError(t, err)
+
+	cmds = dc.Commands()
+	diff := cmds[len(cmds)-1]
+	assert.Equal(t, []string{"git", "diff", "--numstat", "-z"}, diff.Args)
+	assert.NotEmpty(t, diff.Stdout)
+}
+
+func TestDebugCaptureFailure(t *testing.T) {
+	dir := t.TempDir()
+	synthRepo(t, dir, 1)
+	chdir(t, dir)
+
+	var (
+		dc DebugCapture
+		o  = options{capture: &dc}
+	)
+	err := o.runAndParse(context.Background(), new(lines), "git", "rev-parse", "--verify", "no-such-ref")
+	require.Error(t, err)
+
+	cmds := dc.Commands()
+	require.Len(t, cmds, 1)
+	assert.NotZero(t, cmds[0].ExitCode)
+	assert.NotEmpty(t, cmds[0].Stderr)
+	assert.Equal(t, err.Error(), cmds[0].Err)
+}
+
+func TestDebugCaptureJSON(t *testing.T) {
+	var dc DebugCapture
+	dc.add(CapturedCommand{Args: []string{"git", "version"}, Stdout: "git version 2.39.2\n"})
+
+	buf, err := json.Marshal(&dc)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[{"Args":["git","version"],"Duration":0,"ExitCode":0,"Stdout":"git version 2.39.2\n","Stderr":"","Err":""}]`, string(buf))
+}
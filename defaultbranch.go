@@ -0,0 +1,62 @@
+package gitstatus
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+func (st *Status) computeDefaultBranchAheadCount(ctx context.Context) error {
+	ref := st.opts.defaultBranch
+	if ref == "" {
+		var l lines
+		err := st.opts.runAndParse(ctx, &l, "git", "symbolic-ref", "-q", "--short", "refs/remotes/origin/HEAD")
+		if isExitError(err) || (err == nil && len(l) == 0) {
+			// No default branch.
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		ref = strings.TrimSpace(l[0])
+	}
+
+	// origin/HEAD may point to a branch which doesn't exist anymore, and an
+	// explicit ref may be wrong: the default branch is then undetermined.
+	var l lines
+	err := st.opts.runAndParse(ctx, &l, "git", "rev-parse", "--verify", "-q", ref+"^{commit}")
+	if isExitError(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var n count
+	if err := st.opts.runAndParse(ctx, &n, "git", "rev-list", "--count", "HEAD", "^"+ref, "--"); err != nil {
+		return err
+	}
+
+	st.DefaultBranch = ref
+	st.DefaultBranchAheadCount = int(n)
+	return nil
+}
+
+type count int
+
+// parseFrom parses a single decimal number from r.
+func (c *count) parseFrom(r io.Reader) error {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	n, err := strconv.Atoi(strings.TrimSpace(string(b)))
+	if err != nil {
+		return err
+	}
+	*c = count(n)
+	return nil
+}
@@ -0,0 +1,71 @@
+package gitstatus
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+// WithCommitsSinceTag makes New compute LastTag and CommitsSinceTag, that is,
+// the most recent tag reachable from HEAD and the number of commits made
+// since, as git describe does.
+func WithCommitsSinceTag() Option {
+	return func(o *options) { o.commitsSinceTag = true }
+}
+
+// computeCommitsSinceTag sets LastTag and CommitsSinceTag, leaving them empty
+// if no tag is reachable from HEAD.
+func (st *Status) computeCommitsSinceTag(ctx context.Context) error {
+	var d describe
+	err := st.opts.runAndParse(ctx, &d, "git", "describe", "--tags", "--long", "HEAD")
+	if isExitError(err) {
+		// No tag reachable from HEAD.
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	st.LastTag, st.CommitsSinceTag = d.tag, d.distance
+	return nil
+}
+
+// describe holds the output of git describe --long.
+type describe struct {
+	tag      string
+	distance int
+}
+
+// parseFrom parses the output of git describe --long, made of the tag, the
+// number of commits since the tag and the abbreviated commit name, separated
+// by dashes, for example:
+//
+//	v2.3.0-12-g1a2b3c4
+func (d *describe) parseFrom(r io.Reader) error {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	s := strings.TrimSpace(string(b))
+	// Tags may contain dashes, so split from the end.
+	i := strings.LastIndexByte(s, '-')
+	if i <= 0 || !strings.HasPrefix(s[i+1:], "g") {
+		return fmt.Errorf("unexpected git describe output %q", s)
+	}
+	j := strings.LastIndexByte(s[:i], '-')
+	if j <= 0 {
+		return fmt.Errorf("unexpected git describe output %q", s)
+	}
+
+	n, err := strconv.Atoi(s[j+1 : i])
+	if err != nil || n < 0 {
+		return fmt.Errorf("unexpected git describe output %q", s)
+	}
+
+	d.tag, d.distance = s[:j], n
+	return nil
+}
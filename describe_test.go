@@ -0,0 +1,38 @@
+package gitstatus
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDescribeParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		out     string // git describe --long output
+		want    describe
+		wantErr bool
+	}{
+		{name: "on tag", out: "v1.0.0-0-g1a2b3c4\n", want: describe{tag: "v1.0.0"}},
+		{name: "since tag", out: "v2.3.0-12-g1a2b3c4\n", want: describe{tag: "v2.3.0", distance: 12}},
+		{name: "dashes in tag", out: "release-2-rc-1-3-g1a2b3c4\n", want: describe{tag: "release-2-rc-1", distance: 3}},
+		{name: "empty", out: "", wantErr: true},
+		{name: "not long", out: "v1.0.0\n", wantErr: true},
+		{name: "missing tag", out: "-3-g1a2b3c4\n", wantErr: true},
+		{name: "bad distance", out: "v1.0.0-x-g1a2b3c4\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got describe
+			err := got.parseFrom(strings.NewReader(tt.out))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package gitstatus
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrNotARepository is returned when the directory is not inside a git
+// working tree.
+var ErrNotARepository = errors.New("not a git repository")
+
+// insideRepository reports whether a .git directory or file exists in dir or
+// any of its parent directories. The search stops without looking into the
+// ceiling directories, nor their parents.
+func insideRepository(dir string, ceilings []string) bool {
+	dotGit, err := findDotGit(dir, ceilings)
+	if err != nil {
+		// Let git decide.
+		return true
+	}
+	return dotGit != ""
+}
+
+// findDotGit returns the path of the .git directory or file in dir or the
+// closest of its parent directories, or an empty string if there's none. The
+// search stops without looking into the ceiling directories, nor their
+// parents.
+func findDotGit(dir string, ceilings []string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+
+	isCeiling := make(map[string]bool, len(ceilings))
+	for _, c := range ceilings {
+		if c, err := filepath.Abs(c); err == nil {
+			isCeiling[c] = true
+		}
+	}
+
+	for {
+		if exists(dir, ".git") {
+			return filepath.Join(dir, ".git"), nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir || isCeiling[parent] {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// checkRepository returns ErrNotARepository if the directory in which git is
+// run is not inside a repository, so as not to pay the cost of running git.
+// Replayed commands don't depend on the working directory.
+func (o *options) checkRepository() error {
+	if o.replay != nil {
+		return nil
+	}
+
+	wd, err := o.workDir()
+	if err != nil {
+		return err
+	}
+	if !insideRepository(wd, o.ceilings) {
+		return withCode(CodeNotARepository, ErrNotARepository)
+	}
+	return nil
+}
+
+// workDir returns the directory in which git is run.
+func (o *options) workDir() (string, error) {
+	if o.dir != "" {
+		return o.dir, nil
+	}
+	return os.Getwd()
+}
+
+// discoverGitDir returns the git directory git should find from the directory
+// it's run in, without running git, or an empty string if it can't be found.
+// A .git file, as found in linked worktrees and submodules, points to the git
+// directory.
+func (o *options) discoverGitDir() string {
+	if o.replay != nil {
+		return ""
+	}
+
+	wd, err := o.workDir()
+	if err != nil {
+		return ""
+	}
+	dotGit, err := findDotGit(wd, o.ceilings)
+	if err != nil || dotGit == "" {
+		return ""
+	}
+
+	fi, err := os.Stat(dotGit)
+	if err != nil {
+		return ""
+	}
+	if fi.IsDir() {
+		return dotGit
+	}
+
+	b, err := os.ReadFile(dotGit)
+	if err != nil {
+		return ""
+	}
+	s := strings.TrimSpace(string(b))
+	if !strings.HasPrefix(s, "gitdir: ") {
+		return ""
+	}
+	gitDir := strings.TrimPrefix(s, "gitdir: ")
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(filepath.Dir(dotGit), gitDir)
+	}
+	return gitDir
+}
@@ -0,0 +1,58 @@
+package gitstatus
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInsideRepository(t *testing.T) {
+	root := t.TempDir()
+	nested := filepath.Join(root, "repo", "a", "b")
+	require.NoError(t, os.MkdirAll(nested, 0755))
+
+	ceiling := []string{root}
+	assert.False(t, insideRepository(nested, ceiling))
+	assert.False(t, insideRepository(root, ceiling))
+
+	// .git can be a directory...
+	require.NoError(t, os.Mkdir(filepath.Join(root, "repo", ".git"), 0755))
+	assert.True(t, insideRepository(nested, ceiling))
+	assert.True(t, insideRepository(filepath.Join(root, "repo"), ceiling))
+
+	// ...or a file, as in linked worktrees and submodules.
+	require.NoError(t, os.WriteFile(filepath.Join(nested, ".git"), []byte("gitdir: elsewhere\n"), 0644))
+	assert.True(t, insideRepository(nested, []string{filepath.Join(root, "repo", "a")}))
+
+	// The search doesn't go up into ceiling directories.
+	assert.False(t, insideRepository(filepath.Join(root, "repo", "a"), []string{filepath.Join(root, "repo")}))
+}
+
+func TestDiscoverGitDir(t *testing.T) {
+	root := t.TempDir()
+	nested := filepath.Join(root, "repo", "a")
+	require.NoError(t, os.MkdirAll(nested, 0755))
+
+	o := options{dir: nested, ceilings: []string{root}}
+	assert.Empty(t, o.discoverGitDir())
+
+	gitDir := filepath.Join(root, "repo", ".git")
+	require.NoError(t, os.Mkdir(gitDir, 0755))
+	assert.Equal(t, gitDir, o.discoverGitDir())
+
+	// A .git file points to the git directory, relatively to its own.
+	require.NoError(t, os.WriteFile(filepath.Join(nested, ".git"), []byte("gitdir: ../.git/worktrees/a\n"), 0644))
+	assert.Equal(t, filepath.Join(gitDir, "worktrees", "a"), o.discoverGitDir())
+}
+
+func TestNewNotARepository(t *testing.T) {
+	dir := t.TempDir()
+	chdir(t, dir)
+
+	_, err := New(WithCeilingDirectories(filepath.Dir(dir)))
+	assert.Truef(t, errors.Is(err, ErrNotARepository), "got error %v, want %v", err, ErrNotARepository)
+}
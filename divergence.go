@@ -0,0 +1,65 @@
+package gitstatus
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// WithDivergenceFallback makes New compute the divergence of a local branch
+// without upstream branch with the branch of the same name on the origin
+// remote, if it exists. RemoteBranch, AheadCount and BehindCount are then
+// set as if origin/<branch> were the upstream branch, and RemoteBranchGuessed
+// is set.
+//
+// This has no effect if the ahead/behind counts are disabled, as with
+// WithLargeRepoProfile.
+func WithDivergenceFallback() Option {
+	return func(o *options) { o.divergenceFallback = true }
+}
+
+// computeGuessedDivergence computes the divergence with origin/<branch>,
+// leaving st untouched if it doesn't exist.
+func (st *Status) computeGuessedDivergence(ctx context.Context) error {
+	remoteBranch := "origin/" + st.LocalBranch
+
+	var ab aheadBehind
+	err := st.opts.runAndParse(ctx, &ab, "git", "rev-list", "--left-right", "--count",
+		"HEAD...refs/remotes/"+remoteBranch, "--")
+	if isExitError(err) {
+		// No such remote branch.
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	st.RemoteBranch = remoteBranch
+	st.AheadCount, st.BehindCount = ab.ahead, ab.behind
+	st.RemoteBranchGuessed = true
+	return nil
+}
+
+type aheadBehind struct {
+	ahead, behind int
+}
+
+// parseFrom parses the output of git rev-list --left-right --count, that is,
+// the ahead and behind counts separated by a tab.
+func (ab *aheadBehind) parseFrom(r io.Reader) error {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	s := strings.TrimSpace(string(b))
+	if _, err := fmt.Sscanf(s, "%d\t%d", &ab.ahead, &ab.behind); err != nil {
+		return fmt.Errorf("%v: %w", errParseAheadBehind, err)
+	}
+	if ab.ahead < 0 || ab.behind < 0 {
+		return fmt.Errorf(`%v: negative count in "%s"`, errParseAheadBehind, s)
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package gitstatus
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAheadBehindParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		out     string // git rev-list --left-right --count output
+		want    aheadBehind
+		wantErr bool
+	}{
+		{name: "even", out: "0\t0\n", want: aheadBehind{}},
+		{name: "diverged", out: "3\t12\n", want: aheadBehind{ahead: 3, behind: 12}},
+		{name: "empty", out: "", wantErr: true},
+		{name: "single count", out: "3\n", wantErr: true},
+		{name: "negative count", out: "-3\t1\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got aheadBehind
+			err := got.parseFrom(strings.NewReader(tt.out))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
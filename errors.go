@@ -0,0 +1,96 @@
+package gitstatus
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os/exec"
+)
+
+// ErrorCode is a stable, machine-readable, identifier of a category of
+// errors.
+type ErrorCode string
+
+const (
+	// CodeNotARepository is the code of errors occurring when the directory
+	// is not inside a git working tree.
+	CodeNotARepository ErrorCode = "NOT_A_REPO"
+
+	// CodeGitMissing is the code of errors occurring when the git executable
+	// can't be found.
+	CodeGitMissing ErrorCode = "GIT_MISSING"
+
+	// CodeGitTooOld is the code of errors occurring when the git executable,
+	// or a feature it's required for, is too old.
+	CodeGitTooOld ErrorCode = "GIT_TOO_OLD"
+
+	// CodeTimeout is the code of errors occurring when the deadline of the
+	// context expired before git completed.
+	CodeTimeout ErrorCode = "TIMEOUT"
+
+	// CodeLocked is the code of errors occurring when git couldn't lock the
+	// index, or another file, because another git process holds the lock.
+	CodeLocked ErrorCode = "LOCKED"
+
+	// CodeParseError is the code of errors occurring when the output of git
+	// can't be parsed.
+	CodeParseError ErrorCode = "PARSE_ERROR"
+)
+
+// Error is an error with a code. The errors returned by this package for
+// which a code is defined are of type *Error, and can be retrieved with
+// errors.As. Other errors are returned as is.
+type Error struct {
+	Code ErrorCode
+	Err  error
+}
+
+func (e *Error) Error() string { return e.Err.Error() }
+
+// Unwrap returns the underlying error.
+func (e *Error) Unwrap() error { return e.Err }
+
+// Is reports whether e matches target. Errors with CodeNotARepository match
+// ErrNotARepository, whether it's this package or git which found out that
+// the directory is not inside a repository.
+func (e *Error) Is(target error) bool {
+	return e.Code == CodeNotARepository && target == ErrNotARepository
+}
+
+// withCode returns err with the given code.
+func withCode(code ErrorCode, err error) error {
+	return &Error{Code: code, Err: err}
+}
+
+// runErrorCode returns the code of err, returned when running git with ctx,
+// given its standard error output. It returns an empty code if err has no
+// known cause.
+func runErrorCode(ctx context.Context, err error, stderr []byte) ErrorCode {
+	switch {
+	case errors.Is(err, exec.ErrNotFound):
+		return CodeGitMissing
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return CodeTimeout
+	}
+
+	if !isExitError(err) {
+		return ""
+	}
+	switch {
+	case bytes.Contains(stderr, []byte("not a git repository")):
+		return CodeNotARepository
+	case bytes.Contains(stderr, []byte(".lock': File exists")):
+		return CodeLocked
+	}
+	return ""
+}
+
+// ctxErr returns the error of ctx, if it's done, with CodeTimeout if its
+// deadline expired.
+func ctxErr(ctx context.Context) error {
+	err := ctx.Err()
+	if errors.Is(err, context.DeadlineExceeded) {
+		return withCode(CodeTimeout, err)
+	}
+	return err
+}
@@ -0,0 +1,111 @@
+package gitstatus
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingParser is a parserFrom always failing.
+type failingParser struct{}
+
+func (failingParser) parseFrom(io.Reader) error { return errors.New("parse error") }
+
+func TestErrorCodes(t *testing.T) {
+	dir := t.TempDir()
+	synthRepo(t, dir, 1)
+	chdir(t, dir)
+
+	expired, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+
+	tests := []struct {
+		name string
+		run  func(t *testing.T) error
+		want ErrorCode
+		is   error // if set, err must match it
+	}{
+		{
+			name: "not a repository",
+			run: func(t *testing.T) error {
+				notRepo := t.TempDir()
+				_, err := New(WithDir(notRepo), WithCeilingDirectories(filepath.Dir(notRepo)))
+				return err
+			},
+			want: CodeNotARepository,
+			is:   ErrNotARepository,
+		},
+		{
+			name: "not a repository according to git",
+			run: func(t *testing.T) error {
+				var o options
+				return o.runAndParse(context.Background(), new(lines), "git", "--git-dir="+t.TempDir(), "status")
+			},
+			want: CodeNotARepository,
+			is:   ErrNotARepository,
+		},
+		{
+			name: "git missing",
+			run: func(t *testing.T) error {
+				t.Setenv("PATH", "")
+				var o options
+				return o.runAndParse(context.Background(), new(lines), "git", "status")
+			},
+			want: CodeGitMissing,
+		},
+		{
+			name: "timeout",
+			run: func(t *testing.T) error {
+				_, err := NewWithContext(expired)
+				return err
+			},
+			want: CodeTimeout,
+		},
+		{
+			name: "locked",
+			run: func(t *testing.T) error {
+				lock := filepath.Join(dir, ".git", "index.lock")
+				require.NoError(t, os.WriteFile(lock, nil, 0644))
+				defer os.Remove(lock)
+
+				var o options
+				return o.runAndParse(context.Background(), new(lines), "git", "add", ".")
+			},
+			want: CodeLocked,
+		},
+		{
+			name: "parse error",
+			run: func(t *testing.T) error {
+				var o options
+				return o.runAndParse(context.Background(), failingParser{}, "git", "status")
+			},
+			want: CodeParseError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.run(t)
+			var gerr *Error
+			require.Truef(t, errors.As(err, &gerr), "got error %v (%T), want *Error", err, err)
+			assert.Equal(t, tt.want, gerr.Code)
+			if tt.is != nil {
+				assert.Truef(t, errors.Is(err, tt.is), "got error %v, want %v", err, tt.is)
+			}
+		})
+	}
+}
+
+func TestErrorIs(t *testing.T) {
+	// Sentinel errors can still be matched.
+	err := withCode(CodeNotARepository, ErrNotARepository)
+	assert.True(t, errors.Is(err, ErrNotARepository))
+	assert.Equal(t, ErrNotARepository.Error(), err.Error())
+}
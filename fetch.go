@@ -0,0 +1,67 @@
+package gitstatus
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrNetworkDisabled is returned when an option requiring the network is used
+// along with WithNoNetwork.
+var ErrNetworkDisabled = errors.New("network access disabled")
+
+// WithFetch makes New run git fetch, for at most timeout, before computing the
+// status, so that the ahead and behind counts are based on up to date remote
+// branches. git fetches from the remote of the upstream branch, or origin. If
+// timeout is less than or equal to zero, the fetch is only bounded by the
+// context given to New.
+//
+// Failing to fetch, for example because the network or the remote is
+// unreachable, or because the fetch didn't complete in time, is not an error:
+// the status is then computed with the remote branches as they were. New
+// returns ErrNetworkDisabled if WithNoNetwork is also used.
+//
+// git fetch never waits for user input: if the remote requires credentials
+// which can't be obtained from the ssh agent or a credential helper, the
+// fetch fails.
+func WithFetch(timeout time.Duration) Option {
+	return func(o *options) {
+		o.fetch = true
+		o.fetchTimeout = timeout
+	}
+}
+
+// runFetch runs a bounded git fetch, with git version ver, ignoring failures
+// other than ctx being done.
+func (o *options) runFetch(ctx context.Context, ver Version) error {
+	if o.noNetwork {
+		return ErrNetworkDisabled
+	}
+
+	fctx := ctx
+	if o.fetchTimeout > 0 {
+		var cancel context.CancelFunc
+		fctx, cancel = context.WithTimeout(ctx, o.fetchTimeout)
+		defer cancel()
+	}
+
+	// Don't let git start housekeeping tasks in the background.
+	args := []string{"fetch", "--quiet"}
+	switch {
+	case ver.supports(featFetchNoAutoMaintenance):
+		args = append(args, "--no-auto-maintenance")
+	case ver.supports(featFetchNoAutoGC):
+		args = append(args, "--no-auto-gc")
+	}
+
+	fo := *o
+	fo.network = true
+	err := fo.runAndParse(fctx, new(lines), "git", args...)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if isExitError(err) || errors.Is(err, context.DeadlineExceeded) {
+		return nil
+	}
+	return err
+}
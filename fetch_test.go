@@ -0,0 +1,110 @@
+package gitstatus
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetch(t *testing.T) {
+	dir := t.TempDir()
+	remote := filepath.Join(dir, "remote.git")
+	local := filepath.Join(dir, "local")
+	other := filepath.Join(dir, "other")
+	runGit(t, dir, "init", "-q", "--bare", remote)
+	runGit(t, dir, "clone", "-q", remote, local)
+	runGit(t, local, "checkout", "-q", "-b", "main")
+	runGit(t, local, "commit", "-q", "--allow-empty", "-m", "initial commit")
+	runGit(t, local, "push", "-q", "--set-upstream", "origin", "main")
+
+	// Push a commit to the remote, from another clone.
+	runGit(t, dir, "clone", "-q", "--branch", "main", remote, other)
+	runGit(t, other, "commit", "-q", "--allow-empty", "-m", "other commit")
+	runGit(t, other, "push", "-q")
+
+	chdir(t, local)
+
+	st, err := New()
+	require.NoError(t, err)
+	assert.Zero(t, st.BehindCount)
+
+	st, err = New(WithFetch(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 1, st.BehindCount)
+
+	// Without timeout, the fetch still runs.
+	runGit(t, other, "commit", "-q", "--allow-empty", "-m", "another commit")
+	runGit(t, other, "push", "-q")
+	st, err = New(WithFetch(0))
+	require.NoError(t, err)
+	assert.Equal(t, 2, st.BehindCount)
+
+	_, err = New(WithFetch(time.Minute), WithNoNetwork())
+	assert.Truef(t, errors.Is(err, ErrNetworkDisabled), "got error %v, want %v", err, ErrNetworkDisabled)
+}
+
+func TestFetchFailure(t *testing.T) {
+	dir := t.TempDir()
+	synthRepo(t, dir, 1)
+	chdir(t, dir)
+
+	runGit(t, dir, "remote", "add", "origin", filepath.Join(dir, "no-such-remote"))
+
+	// Failing to fetch doesn't prevent computing the status.
+	_, err := New(WithFetch(time.Minute))
+	assert.NoError(t, err)
+}
+
+func TestFetchAuthRequired(t *testing.T) {
+	dir := t.TempDir()
+	synthRepo(t, dir, 1)
+	chdir(t, dir)
+
+	// A remote asking for credentials, which git has no way to get.
+	var asked int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.StoreInt32(&asked, 1)
+		w.Header().Set("WWW-Authenticate", `Basic realm="gitstatus"`)
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	runGit(t, dir, "remote", "add", "origin", srv.URL+"/repo.git")
+
+	// git fails instead of waiting for credentials until the timeout.
+	start := time.Now()
+	_, err := New(WithFetch(time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&asked))
+	assert.Truef(t, time.Since(start) < 30*time.Second, "fetch took %v", time.Since(start))
+}
+
+func TestFetchEnv(t *testing.T) {
+	t.Setenv("SSH_AUTH_SOCK", "/tmp/agent.sock")
+	for _, name := range []string{"GIT_SSH", "GIT_SSH_COMMAND"} {
+		t.Setenv(name, "") // restored after the test
+		os.Unsetenv(name)
+	}
+
+	env := (&options{network: true}).env()
+	assert.Contains(t, env, "SSH_AUTH_SOCK=/tmp/agent.sock")
+	assert.Contains(t, env, "GIT_TERMINAL_PROMPT=0")
+	assert.Contains(t, env, "GIT_SSH_COMMAND=ssh -o BatchMode=yes")
+
+	// The ssh command chosen by the user is kept.
+	t.Setenv("GIT_SSH_COMMAND", "ssh -i key")
+	env = (&options{network: true}).env()
+	assert.Contains(t, env, "GIT_SSH_COMMAND=ssh -i key")
+	assert.NotContains(t, env, "GIT_SSH_COMMAND=ssh -o BatchMode=yes")
+
+	// Other git commands don't get those variables.
+	assert.NotContains(t, (&options{}).env(), "SSH_AUTH_SOCK=/tmp/agent.sock")
+}
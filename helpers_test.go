@@ -0,0 +1,77 @@
+package gitstatus
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// runGit runs git with args in dir, failing the test or benchmark on error.
+// Commits are made with a fixed identity.
+func runGit(tb testing.TB, dir string, args ...string) {
+	tb.Helper()
+
+	args = append([]string{"-c", "user.email=i@example.com", "-c", "user.name=someone"}, args...)
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		tb.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+}
+
+// synthRepo creates, under dir, a git repository with nfiles committed files.
+// Then a tenth of them are modified, a twentieth are staged, and a tenth of
+// nfiles untracked files are added.
+func synthRepo(tb testing.TB, dir string, nfiles int) {
+	tb.Helper()
+
+	write := func(name, content string) {
+		tb.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			tb.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			tb.Fatal(err)
+		}
+	}
+	// Spread files in directories of 100 files.
+	name := func(i int) string { return fmt.Sprintf("dir%03d/file%05d", i/100, i) }
+
+	runGit(tb, dir, "init", "-q")
+	runGit(tb, dir, "config", "user.email", "i@example.com")
+	runGit(tb, dir, "config", "user.name", "someone")
+	for i := 0; i < nfiles; i++ {
+		write(name(i), "line1\nline2\n")
+	}
+	runGit(tb, dir, "add", ".")
+	runGit(tb, dir, "commit", "-q", "-m", "initial commit")
+
+	for i := 0; i < nfiles; i += 10 {
+		write(name(i), "line1\nline2\nline3\n")
+	}
+	for i := 5; i < nfiles; i += 20 {
+		write(name(i), "line1\n")
+		runGit(tb, dir, "add", name(i))
+	}
+	for i := 0; i < nfiles/10; i++ {
+		write(fmt.Sprintf("untracked/file%05d", i), "untracked\n")
+	}
+}
+
+// chdir changes the current working directory to dir, and restores it when
+// the test or benchmark ends.
+func chdir(tb testing.TB, dir string) {
+	tb.Helper()
+
+	wd, err := os.Getwd()
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { os.Chdir(wd) })
+}
@@ -0,0 +1,116 @@
+package gitstatus
+
+import "context"
+
+// pendingFields is a bit set of the Status fields not yet computed in lazy
+// mode.
+type pendingFields uint8
+
+const (
+	pendingDiffStats pendingFields = 1 << iota
+	pendingStashCount
+	pendingTreeState
+	pendingUpstreamDiffStats
+)
+
+// DiffStats returns the count of inserted and deleted lines in the staging
+// area.
+//
+// If the Status has been created with WithLazy, the first call computes, then
+// fills Insertions and Deletions. Otherwise DiffStats returns their values.
+// DiffStats is not safe for concurrent use.
+func (st *Status) DiffStats(ctx context.Context) (insertions, deletions int, err error) {
+	if st.pending&pendingDiffStats != 0 {
+		if err := st.computeDiffStats(ctx); err != nil {
+			return 0, 0, err
+		}
+	}
+	return st.Insertions, st.Deletions, nil
+}
+
+// UpstreamDiffStats returns the count of inserted and deleted lines by the
+// commits of the local branch not yet in its upstream branch. Both are 0 if
+// the Status hasn't been created with WithUpstreamDiffStats.
+//
+// If the Status has been created with WithLazy, the first call computes, then
+// fills UpstreamInsertions and UpstreamDeletions. Otherwise UpstreamDiffStats
+// returns their values. UpstreamDiffStats is not safe for concurrent use.
+func (st *Status) UpstreamDiffStats(ctx context.Context) (insertions, deletions int, err error) {
+	if st.pending&pendingUpstreamDiffStats != 0 {
+		if err := st.computeUpstreamDiffStats(ctx); err != nil {
+			return 0, 0, err
+		}
+	}
+	return st.UpstreamInsertions, st.UpstreamDeletions, nil
+}
+
+// StashCount returns the number of stash entries.
+//
+// If the Status has been created with WithLazy, the first call computes, then
+// fills NumStashed. Otherwise StashCount returns its value. StashCount is not
+// safe for concurrent use.
+func (st *Status) StashCount(ctx context.Context) (int, error) {
+	if st.pending&pendingStashCount != 0 {
+		if err := st.computeStashCount(ctx); err != nil {
+			return 0, err
+		}
+	}
+	return st.NumStashed, nil
+}
+
+// TreeState returns the state of the working tree.
+//
+// If the Status has been created with WithLazy, the first call computes, then
+// fills State. Otherwise TreeState returns its value. TreeState is not safe
+// for concurrent use.
+func (st *Status) TreeState(ctx context.Context) (TreeState, error) {
+	if st.pending&pendingTreeState != 0 {
+		if err := ctxErr(ctx); err != nil {
+			return Default, err
+		}
+		st.State = treeStateFromDir(st.GitDir)
+		st.pending &^= pendingTreeState
+	}
+	return st.State, nil
+}
+
+func (st *Status) computeDiffStats(ctx context.Context) error {
+	args := []string{"diff", "--numstat", "-z"}
+	if flag := st.opts.ignoreSubmodules.flag(); flag != "" {
+		args = append(args, flag)
+	}
+
+	stats := stats{}
+	if err := st.opts.runAndParse(ctx, &stats, "git", args...); err != nil {
+		return err
+	}
+	st.Insertions, st.Deletions = stats.insertions, stats.deletions
+	st.pending &^= pendingDiffStats
+	return nil
+}
+
+func (st *Status) computeUpstreamDiffStats(ctx context.Context) error {
+	args := []string{"diff", "--numstat", "-z"}
+	if flag := st.opts.ignoreSubmodules.flag(); flag != "" {
+		args = append(args, flag)
+	}
+	args = append(args, "@{upstream}...HEAD")
+
+	stats := stats{}
+	if err := st.opts.runAndParse(ctx, &stats, "git", args...); err != nil {
+		return err
+	}
+	st.UpstreamInsertions, st.UpstreamDeletions = stats.insertions, stats.deletions
+	st.pending &^= pendingUpstreamDiffStats
+	return nil
+}
+
+func (st *Status) computeStashCount(ctx context.Context) error {
+	nstashed := linecount(0)
+	if err := st.opts.runAndParse(ctx, &nstashed, "git", "stash", "list"); err != nil {
+		return err
+	}
+	st.NumStashed = int(nstashed)
+	st.pending &^= pendingStashCount
+	return nil
+}
@@ -0,0 +1,59 @@
+package gitstatus
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLazy(t *testing.T) {
+	dir := t.TempDir()
+	synthRepo(t, dir, 20)
+	chdir(t, dir)
+
+	ctx := context.Background()
+
+	want, err := New()
+	require.NoError(t, err)
+
+	got, err := New(WithLazy())
+	require.NoError(t, err)
+
+	assert.Zero(t, got.Insertions)
+	assert.Zero(t, got.Deletions)
+	assert.Equal(t, want.Porcelain, got.Porcelain)
+	assert.Equal(t, want.HEAD, got.HEAD)
+
+	ins, del, err := got.DiffStats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, want.Insertions, ins)
+	assert.Equal(t, want.Deletions, del)
+
+	nstashed, err := got.StashCount(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, want.NumStashed, nstashed)
+
+	state, err := got.TreeState(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, want.State, state)
+
+	// All fields have now been computed.
+	assert.Equal(t, exportedFields(want), exportedFields(got))
+
+	// Methods return fields values in eager mode.
+	ins, del, err = want.DiffStats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, want.Insertions, ins)
+	assert.Equal(t, want.Deletions, del)
+}
+
+// exportedFields returns a copy of st with unexported fields cleared.
+func exportedFields(st *Status) Status {
+	cpy := *st
+	cpy.opts = nil
+	cpy.pending = 0
+	cpy.refs = refsStamp{}
+	return cpy
+}
@@ -0,0 +1,253 @@
+package gitstatus
+
+import (
+	"path/filepath"
+	"time"
+)
+
+// Option configures how the Status of a working tree is computed.
+type Option func(*options)
+
+type options struct {
+	lazy          bool
+	untracked     UntrackedMode
+	ceilings      []string
+	noDiffStats   bool
+	noAheadBehind bool
+	capture       *DebugCapture
+	upstreamStats bool
+
+	defaultBranch        string
+	defaultBranchEnabled bool
+
+	dir string
+
+	ignoreSubmodules SubmoduleIgnore
+	noNetwork        bool
+
+	fetch        bool
+	fetchTimeout time.Duration
+	network      bool // set on the options of git fetch only
+
+	divergenceFallback bool
+	commitsSinceTag    bool
+
+	replay *replayer
+
+	outputLimit int
+	lowPriority bool
+	indexFile   string
+}
+
+// WithLazy makes New only compute the fields it can't do without, deferring
+// the computation of the most expensive ones until they're requested.
+//
+// In lazy mode, Insertions, Deletions, NumStashed and State are left to their
+// zero value by New, and are filled when calling DiffStats, StashCount and
+// TreeState, respectively.
+func WithLazy() Option {
+	return func(o *options) { o.lazy = true }
+}
+
+// UntrackedMode controls how git status reports untracked files.
+type UntrackedMode int
+
+const (
+	// UntrackedDefault leaves git choose, according to the
+	// status.showUntrackedFiles configuration variable.
+	UntrackedDefault UntrackedMode = iota
+
+	// UntrackedNo doesn't report untracked files, so NumUntracked is
+	// always 0. This makes status much faster on huge working trees.
+	UntrackedNo
+
+	// UntrackedNormal reports untracked files and directories, a directory
+	// with untracked files counting as a single untracked file.
+	UntrackedNormal
+
+	// UntrackedAll reports each untracked file, including those in untracked
+	// directories.
+	UntrackedAll
+)
+
+// flag returns the git status command-line flag corresponding to the mode, or
+// an empty string for UntrackedDefault.
+func (m UntrackedMode) flag() string {
+	switch m {
+	case UntrackedNo:
+		return "--untracked-files=no"
+	case UntrackedNormal:
+		return "--untracked-files=normal"
+	case UntrackedAll:
+		return "--untracked-files=all"
+	}
+	return ""
+}
+
+// WithUntrackedFiles sets how untracked files are reported and counted in
+// NumUntracked.
+func WithUntrackedFiles(mode UntrackedMode) Option {
+	return func(o *options) { o.untracked = mode }
+}
+
+// WithCeilingDirectories sets directories New doesn't walk up into while
+// looking for a .git directory or file, like GIT_CEILING_DIRECTORIES does for
+// git. If none has been found before reaching one of those, or the root
+// directory, New returns ErrNotARepository without running git.
+func WithCeilingDirectories(dirs ...string) Option {
+	return func(o *options) { o.ceilings = append(o.ceilings, dirs...) }
+}
+
+// WithLargeRepoProfile tunes the computation of the status for very large
+// working trees, trading some information for latency:
+//   - untracked files are not reported (as with UntrackedNo), so NumUntracked
+//     is always 0,
+//   - diff stats are not computed, so Insertions and Deletions are always 0,
+//   - with git 2.17 and later, the ahead/behind counts are not computed, so
+//     AheadCount and BehindCount are always 0.
+//
+// Options given after WithLargeRepoProfile override its settings. The file
+// system monitor (core.fsmonitor) and untracked cache (core.untrackedCache)
+// are configured in the repository and honored by git.
+func WithLargeRepoProfile() Option {
+	return func(o *options) {
+		o.untracked = UntrackedNo
+		o.noDiffStats = true
+		o.noAheadBehind = true
+	}
+}
+
+// WithUpstreamDiffStats makes New compute UpstreamInsertions and
+// UpstreamDeletions, that is, the size of the changes committed on the local
+// branch but not on its upstream branch.
+func WithUpstreamDiffStats() Option {
+	return func(o *options) { o.upstreamStats = true }
+}
+
+// WithDefaultBranchAheadCount makes New compute DefaultBranchAheadCount, the
+// number of commits of HEAD not contained in ref, the default branch.
+//
+// If ref is empty, the default branch is the one the origin remote HEAD
+// points to (refs/remotes/origin/HEAD). If it can't be determined, or if it
+// doesn't resolve to a commit, DefaultBranch and DefaultBranchAheadCount are
+// left empty.
+func WithDefaultBranchAheadCount(ref string) Option {
+	return func(o *options) {
+		o.defaultBranch = ref
+		o.defaultBranchEnabled = true
+	}
+}
+
+// WithDir makes New compute the status of the working tree containing dir,
+// rather than the one containing the current working directory.
+func WithDir(dir string) Option {
+	return func(o *options) { o.dir = dir }
+}
+
+// SubmoduleIgnore controls which changes of submodules are ignored.
+type SubmoduleIgnore string
+
+const (
+	// IgnoreSubmodulesNone considers a submodule modified when it contains
+	// untracked or modified files, or its HEAD differs from the commit
+	// recorded in the superproject.
+	IgnoreSubmodulesNone SubmoduleIgnore = "none"
+
+	// IgnoreSubmodulesUntracked doesn't consider a submodule modified when it
+	// only contains untracked files.
+	IgnoreSubmodulesUntracked SubmoduleIgnore = "untracked"
+
+	// IgnoreSubmodulesDirty only considers a submodule modified when its HEAD
+	// differs from the commit recorded in the superproject.
+	IgnoreSubmodulesDirty SubmoduleIgnore = "dirty"
+
+	// IgnoreSubmodulesAll ignores all changes to submodules.
+	IgnoreSubmodulesAll SubmoduleIgnore = "all"
+)
+
+// flag returns the git status and git diff command-line flag corresponding to
+// the mode, or an empty string if no mode has been set.
+func (m SubmoduleIgnore) flag() string {
+	if m == "" {
+		return ""
+	}
+	return "--ignore-submodules=" + string(m)
+}
+
+// WithIgnoreSubmodules sets which changes of submodules are ignored when
+// computing the status and the diff stats. By default, git decides according
+// to the diff.ignoreSubmodules and submodule.<name>.ignore configuration
+// variables.
+func WithIgnoreSubmodules(mode SubmoduleIgnore) Option {
+	return func(o *options) { o.ignoreSubmodules = mode }
+}
+
+// WithNoNetwork prevents git, as far as its version allows, from accessing
+// the network or waiting for user input while computing the status.
+//
+// Except git fetch, run with WithFetch, all the commands run by this package
+// are local, but git may still try to fetch the objects missing from a
+// partial clone, or to ask for credentials. WithNoNetwork disables those, as
+// well as credential helpers, and makes WithFetch an error.
+//
+// This is best-effort: credential helpers are only disabled with git 2.31 and
+// later, and the fetch of missing objects with git 2.45 and later. Older
+// versions ignore those settings.
+func WithNoNetwork() Option {
+	return func(o *options) { o.noNetwork = true }
+}
+
+// WithOutputLimit limits to n bytes the output of git status kept in memory,
+// guarding against working trees with a huge number of changed or untracked
+// files. The entries beyond the limit are not counted and Truncated is set,
+// so that NumModified, NumStaged, NumConflicts and NumUntracked are lower
+// bounds. The header of the output, with the branch information, is always
+// kept whole. Limits less than or equal to zero mean no limit, the default.
+//
+// Only the output of git status is limited: that of git diff, from which
+// Insertions and Deletions are computed, is read whole. Use WithReducedStat
+// to disable the diff stats as well.
+func WithOutputLimit(n int) Option {
+	return func(o *options) { o.outputLimit = n }
+}
+
+// WithReducedStat tunes the computation of the status for working trees on
+// network file systems, such as NFS or SMB, where each file system access is
+// slow, by avoiding those not strictly needed:
+//   - untracked files are not looked for (as with UntrackedNo), so
+//     NumUntracked is always 0,
+//   - diff stats are not computed, so Insertions and Deletions are always 0,
+//   - submodules are not looked into (as with IgnoreSubmodulesAll).
+//
+// Options given after WithReducedStat override its settings. git still checks
+// the files of the index, but only those whose cached file system information
+// changed are read.
+func WithReducedStat() Option {
+	return func(o *options) {
+		o.untracked = UntrackedNo
+		o.noDiffStats = true
+		o.ignoreSubmodules = IgnoreSubmodulesAll
+	}
+}
+
+// WithLowPriority runs git with a lower CPU priority, and on Linux, a lower
+// I/O priority, so that computing the status in the background doesn't slow
+// down other programs. This is best effort, and has no effect on platforms
+// other than Linux, BSDs, macOS and Windows.
+func WithLowPriority() Option {
+	return func(o *options) { o.lowPriority = true }
+}
+
+// WithIndexFile makes New use the index file at path, as with the
+// GIT_INDEX_FILE environment variable, instead of the index of the
+// repository. This is useful to show the state of a temporary index, such as
+// those built by partial commit tools. A relative path is relative to the
+// current working directory.
+func WithIndexFile(path string) Option {
+	return func(o *options) {
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
+		o.indexFile = path
+	}
+}
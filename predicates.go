@@ -0,0 +1,50 @@
+package gitstatus
+
+// HasConflicts reports whether there are unmerged files.
+func (st *Status) HasConflicts() bool {
+	return st.NumConflicts > 0
+}
+
+// HasStagedChanges reports whether there are changes in the staging area,
+// ready to be committed.
+func (st *Status) HasStagedChanges() bool {
+	return st.NumStaged > 0
+}
+
+// HasUnstagedChanges reports whether tracked files have been modified or
+// deleted in the working tree, but the changes haven't been staged. Unmerged
+// and untracked files are not considered.
+func (st *Status) HasUnstagedChanges() bool {
+	return st.NumModified > 0
+}
+
+// NeedsPush reports whether the local branch has commits its upstream branch
+// doesn't have. It's always false for branches without upstream.
+func (st *Status) NeedsPush() bool {
+	return st.RemoteBranch != "" && st.AheadCount > 0
+}
+
+// NeedsPull reports whether the upstream branch has commits the local branch
+// doesn't have. It's always false for branches without upstream.
+func (st *Status) NeedsPull() bool {
+	return st.RemoteBranch != "" && st.BehindCount > 0
+}
+
+// InProgressOperation reports whether an operation, such as a rebase, a merge
+// or a bisect, is in progress. The operation is given by State. In lazy mode,
+// TreeState must have been called first.
+func (st *Status) InProgressOperation() bool {
+	return st.State != Default
+}
+
+// ReadyToContinue reports whether an operation which can be continued, such
+// as a rebase, a merge or a cherry-pick, is in progress and all conflicts have
+// been resolved, so that the operation can be continued with --continue. In
+// lazy mode, TreeState must have been called first.
+func (st *Status) ReadyToContinue() bool {
+	switch st.State {
+	case Default, Bisecting:
+		return false
+	}
+	return !st.HasConflicts()
+}
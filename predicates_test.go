@@ -0,0 +1,66 @@
+package gitstatus
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPredicates(t *testing.T) {
+	tests := []struct {
+		name string
+		st   Status
+
+		conflicts, staged, unstaged, push, pull, inProgress, readyToContinue bool
+	}{
+		{
+			name: "clean",
+			st:   Status{Porcelain: Porcelain{LocalBranch: "main", RemoteBranch: "origin/main"}, IsClean: true},
+		},
+		{
+			name:      "rebase with conflicts",
+			st:        Status{Porcelain: Porcelain{IsDetached: true, NumConflicts: 2}, State: Rebasing},
+			conflicts: true, inProgress: true,
+		},
+		{
+			name:   "rebase with resolved conflicts",
+			st:     Status{Porcelain: Porcelain{IsDetached: true, NumStaged: 2}, State: Rebasing},
+			staged: true, inProgress: true, readyToContinue: true,
+		},
+		{
+			name:       "bisect",
+			st:         Status{Porcelain: Porcelain{IsDetached: true}, State: Bisecting},
+			inProgress: true,
+		},
+		{
+			name:   "staged and unstaged",
+			st:     Status{Porcelain: Porcelain{LocalBranch: "main", NumStaged: 1, NumModified: 3}},
+			staged: true, unstaged: true,
+		},
+		{
+			name: "untracked only",
+			st:   Status{Porcelain: Porcelain{LocalBranch: "main", NumUntracked: 1}},
+		},
+		{
+			name: "diverged",
+			st:   Status{Porcelain: Porcelain{LocalBranch: "main", RemoteBranch: "origin/main", AheadCount: 1, BehindCount: 2}},
+			push: true, pull: true,
+		},
+		{
+			name: "no upstream",
+			st:   Status{Porcelain: Porcelain{LocalBranch: "main", AheadCount: 1}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.conflicts, tt.st.HasConflicts(), "HasConflicts")
+			assert.Equal(t, tt.staged, tt.st.HasStagedChanges(), "HasStagedChanges")
+			assert.Equal(t, tt.unstaged, tt.st.HasUnstagedChanges(), "HasUnstagedChanges")
+			assert.Equal(t, tt.push, tt.st.NeedsPush(), "NeedsPush")
+			assert.Equal(t, tt.pull, tt.st.NeedsPull(), "NeedsPull")
+			assert.Equal(t, tt.inProgress, tt.st.InProgressOperation(), "InProgressOperation")
+			assert.Equal(t, tt.readyToContinue, tt.st.ReadyToContinue(), "ReadyToContinue")
+		})
+	}
+}
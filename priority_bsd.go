@@ -0,0 +1,29 @@
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd
+
+package gitstatus
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+const (
+	lowPriorityNiceness = 10
+	maxNiceness         = 20
+)
+
+// startLowPriority starts cmd then lowers its CPU priority. This is best
+// effort, cmd may have already exited.
+func startLowPriority(cmd *exec.Cmd) error {
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	if niceness, err := syscall.Getpriority(syscall.PRIO_PROCESS, 0); err == nil {
+		niceness += lowPriorityNiceness
+		if niceness > maxNiceness {
+			niceness = maxNiceness
+		}
+		syscall.Setpriority(syscall.PRIO_PROCESS, cmd.Process.Pid, niceness)
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package gitstatus
+
+import (
+	"os/exec"
+	"runtime"
+	"syscall"
+)
+
+const (
+	ioprioWhoProcess    = 1
+	ioprioClassIdle     = 3
+	ioprioClassShift    = 13
+	lowPriorityNiceness = 10
+	maxNiceness         = 19
+)
+
+// startLowPriority starts cmd with a lower CPU priority, and an idle I/O
+// scheduling class.
+//
+// On Linux, both are attributes of threads, inherited by the processes they
+// create. cmd is started from a dedicated thread whose priority is lowered
+// beforehand, so that cmd runs with a low priority from the start. Since the
+// priority of the thread can't be raised back, it exits afterwards.
+func startLowPriority(cmd *exec.Cmd) error {
+	errc := make(chan error, 1)
+	go func() {
+		// The thread is not unlocked, so that it exits with the goroutine.
+		runtime.LockOSThread()
+
+		tid := syscall.Gettid()
+		// The kernel reports priorities as 20 - niceness.
+		if prio, err := syscall.Getpriority(syscall.PRIO_PROCESS, tid); err == nil {
+			niceness := 20 - prio + lowPriorityNiceness
+			if niceness > maxNiceness {
+				niceness = maxNiceness
+			}
+			syscall.Setpriority(syscall.PRIO_PROCESS, tid, niceness)
+		}
+		syscall.Syscall(syscall.SYS_IOPRIO_SET, ioprioWhoProcess, uintptr(tid), ioprioClassIdle<<ioprioClassShift)
+
+		errc <- cmd.Start()
+	}()
+	return <-errc
+}
@@ -0,0 +1,46 @@
+package gitstatus
+
+import (
+	"context"
+	"os/exec"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLowPriority(t *testing.T) {
+	if _, err := exec.LookPath("nice"); err != nil {
+		t.Skip("nice not found")
+	}
+
+	out, err := exec.Command("nice").Output()
+	require.NoError(t, err)
+	parent, err := strconv.Atoi(string(out[:len(out)-1]))
+	require.NoError(t, err)
+
+	want := parent + lowPriorityNiceness
+	if want > maxNiceness {
+		want = maxNiceness
+	}
+
+	var o options
+	WithLowPriority()(&o)
+
+	// The priority is lowered before the command starts.
+	var niceness lines
+	require.NoError(t, o.runAndParse(context.Background(), &niceness, "nice"))
+	assert.Equal(t, lines{strconv.Itoa(want)}, niceness)
+
+	if _, err := exec.LookPath("ionice"); err == nil {
+		var class lines
+		require.NoError(t, o.runAndParse(context.Background(), &class, "ionice"))
+		assert.Equal(t, lines{"idle"}, class)
+	}
+
+	// Commands run without WithLowPriority are not affected.
+	niceness = nil
+	require.NoError(t, (&options{}).runAndParse(context.Background(), &niceness, "nice"))
+	assert.Equal(t, lines{strconv.Itoa(parent)}, niceness)
+}
@@ -0,0 +1,7 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd && !windows
+
+package gitstatus
+
+import "os/exec"
+
+func startLowPriority(cmd *exec.Cmd) error { return cmd.Start() }
@@ -0,0 +1,17 @@
+package gitstatus
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+const belowNormalPriorityClass = 0x00004000
+
+// startLowPriority starts cmd with a below normal priority class.
+func startLowPriority(cmd *exec.Cmd) error {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.CreationFlags |= belowNormalPriorityClass
+	return cmd.Start()
+}
@@ -0,0 +1,104 @@
+package gitstatus
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Refresh updates st in place with the current status of its working tree,
+// using the options st has been created with.
+//
+// git status is always run, since any file of the working tree may have
+// changed. However, the commands depending only on the refs, such as those
+// computing HEAD and NumStashed, are not run again if the refs they depend
+// on haven't changed. In lazy mode, the fields not computed by New are reset
+// to their zero value, until requested again.
+//
+// If Refresh returns an error, st is left untouched. Refresh is not safe for
+// concurrent use.
+func (st *Status) Refresh(ctx context.Context) error { return st.update(ctx) }
+
+// fileStamp identifies a version of a file, the zero value being that of a
+// missing file.
+//
+// git updates a ref by renaming a new file over it, so the inode number tells
+// versions apart even when they have the same size and were written within
+// the time granularity of the file system.
+type fileStamp struct {
+	modTime int64 // in nanoseconds since the epoch
+	size    int64
+	ino     uint64 // 0 where not available
+}
+
+func stampFile(path string) fileStamp {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}
+	}
+	return fileStamp{modTime: fi.ModTime().UnixNano(), size: fi.Size(), ino: inode(fi)}
+}
+
+// sameFile reports whether paths a and b exist and are the same file.
+func sameFile(a, b string) bool {
+	if a == "" || b == "" {
+		return false
+	}
+	fa, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	fb, err := os.Stat(b)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(fa, fb)
+}
+
+// refsStamp identifies the state of the refs of a repository.
+type refsStamp struct {
+	// valid is false if the refs can't be stamped, as with the reftable
+	// backend.
+	valid bool
+
+	head struct {
+		content string    // of the HEAD file
+		ref     fileStamp // loose ref file HEAD points to
+		packed  fileStamp // packed-refs file
+	}
+
+	stash fileStamp // stash reflog
+}
+
+// stampRefs returns the stamp of the refs of the repository whose git
+// directory is gitDir.
+func stampRefs(gitDir string) refsStamp {
+	var rs refsStamp
+
+	// The refs of linked worktrees, except HEAD, are in the common directory.
+	commonDir := gitDir
+	if b, err := os.ReadFile(filepath.Join(gitDir, "commondir")); err == nil {
+		commonDir = strings.TrimSpace(string(b))
+		if !filepath.IsAbs(commonDir) {
+			commonDir = filepath.Join(gitDir, commonDir)
+		}
+	}
+	if exists(commonDir, "reftable") {
+		return rs
+	}
+
+	head, err := os.ReadFile(filepath.Join(gitDir, "HEAD"))
+	if err != nil {
+		return rs
+	}
+	rs.head.content = string(head)
+	if s := strings.TrimSpace(rs.head.content); strings.HasPrefix(s, "ref: ") {
+		ref := strings.TrimPrefix(s, "ref: ")
+		rs.head.ref = stampFile(filepath.Join(commonDir, filepath.FromSlash(ref)))
+	}
+	rs.head.packed = stampFile(filepath.Join(commonDir, "packed-refs"))
+	rs.stash = stampFile(filepath.Join(commonDir, "logs", "refs", "stash"))
+	rs.valid = true
+	return rs
+}
@@ -0,0 +1,111 @@
+package gitstatus
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRefresh(t *testing.T) {
+	dir := t.TempDir()
+	synthRepo(t, dir, 20)
+	chdir(t, dir)
+
+	// ran reports whether the git subcommand has been captured, then resets
+	// the capture.
+	var dc DebugCapture
+	ran := func(subcmd string) bool {
+		defer func() { dc = DebugCapture{} }()
+		for _, cmd := range dc.Commands() {
+			if len(cmd.Args) > 1 && cmd.Args[1] == subcmd {
+				return true
+			}
+		}
+		return false
+	}
+
+	ctx := context.Background()
+	st, err := New(WithDebugCapture(&dc))
+	require.NoError(t, err)
+	dc = DebugCapture{}
+
+	checkRefresh := func() {
+		t.Helper()
+		require.NoError(t, st.Refresh(ctx))
+		want, err := New()
+		require.NoError(t, err)
+		assert.Equal(t, exportedFields(want), exportedFields(st))
+	}
+
+	// Nothing changed: the commands depending on refs are not run.
+	checkRefresh()
+	assert.False(t, ran("rev-parse"), "rev-parse should not run")
+	assert.False(t, ran("stash"), "stash list should not run")
+
+	// Modify the working tree only.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "refresh"), []byte("new\n"), 0644))
+	checkRefresh()
+	assert.False(t, ran("rev-parse"), "rev-parse should not run")
+
+	// Commit, HEAD changes.
+	runGit(t, dir, "add", ".")
+	runGit(t, dir, "commit", "-q", "-m", "refresh")
+	checkRefresh()
+	assert.True(t, ran("rev-parse"), "rev-parse should run")
+	assert.False(t, ran("diff"), "diff should not run on a clean tree")
+
+	// Stash, the stash count changes.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "refresh"), []byte("stashed\n"), 0644))
+	runGit(t, dir, "stash", "-q")
+	checkRefresh()
+	assert.True(t, ran("stash"), "stash list should run")
+	assert.Equal(t, 1, st.NumStashed)
+}
+
+func TestRefreshLazy(t *testing.T) {
+	dir := t.TempDir()
+	synthRepo(t, dir, 20)
+	chdir(t, dir)
+
+	ctx := context.Background()
+	st, err := New(WithLazy())
+	require.NoError(t, err)
+	_, _, err = st.DiffStats(ctx)
+	require.NoError(t, err)
+
+	require.NoError(t, st.Refresh(ctx))
+	assert.Zero(t, st.Insertions)
+	assert.Zero(t, st.Deletions)
+
+	want, err := New()
+	require.NoError(t, err)
+	ins, del, err := st.DiffStats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, want.Insertions, ins)
+	assert.Equal(t, want.Deletions, del)
+}
+
+func TestStampFileReplaced(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "ref")
+	require.NoError(t, os.WriteFile(path, []byte("0123456\n"), 0644))
+	fi, err := os.Stat(path)
+	require.NoError(t, err)
+	if inode(fi) == 0 {
+		t.Skip("inode numbers are not available")
+	}
+	before := stampFile(path)
+
+	// Replace the file, as git does, with one of the same size and
+	// modification time.
+	tmp := filepath.Join(dir, "ref.lock")
+	require.NoError(t, os.WriteFile(tmp, []byte("abcdef0\n"), 0644))
+	require.NoError(t, os.Chtimes(tmp, fi.ModTime(), fi.ModTime()))
+	require.NoError(t, os.Rename(tmp, path))
+
+	assert.NotEqual(t, before, stampFile(path))
+}
@@ -0,0 +1,134 @@
+package gitstatus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// WithReplay makes New compute the Status from the commands recorded in dc,
+// for example with WithDebugCapture on another machine, rather than by
+// running git. It's meant to reproduce issues without access to the original
+// repository.
+//
+// Each command New would run is looked up among the recorded commands with
+// the exact same arguments, so the options given to New must be those used
+// when recording. If the git version hasn't been recorded, that of the local
+// git executable is assumed. The state of the working tree isn't recorded:
+// State is computed from the git directory at GitDir on the local file system,
+// which usually doesn't exist, leaving State to Default. A nil dc is replayed
+// as an empty capture.
+func WithReplay(dc *DebugCapture) Option {
+	return func(o *options) {
+		o.replay = &replayer{}
+		if dc != nil {
+			o.replay.commands = dc.Commands()
+		}
+	}
+}
+
+// UnmarshalJSON replaces the recorded commands with those decoded from b, in
+// the format produced by MarshalJSON.
+func (dc *DebugCapture) UnmarshalJSON(b []byte) error {
+	var cmds []CapturedCommand
+	if err := json.Unmarshal(b, &cmds); err != nil {
+		return err
+	}
+
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	dc.commands = cmds
+	return nil
+}
+
+// replayer replays recorded commands, each at most once.
+type replayer struct {
+	mu       sync.Mutex
+	commands []CapturedCommand
+	replayed []bool
+}
+
+// replayExitError is the error of a replayed command which exited with a
+// non-zero exit code.
+type replayExitError struct {
+	code   int
+	stderr string
+}
+
+func (e *replayExitError) Error() string { return fmt.Sprintf("exit status %d", e.code) }
+
+// take returns the first not yet replayed command with the given command line
+// and marks it as replayed. It reports false if there's none.
+func (r *replayer) take(cmdline []string) (CapturedCommand, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.replayed == nil {
+		r.replayed = make([]bool, len(r.commands))
+	}
+	for i, cmd := range r.commands {
+		if !r.replayed[i] && equalArgs(cmd.Args, cmdline) {
+			r.replayed[i] = true
+			return cmd, true
+		}
+	}
+	return CapturedCommand{}, false
+}
+
+// lookup returns the first command recorded with the given command line,
+// replayed or not, without marking it as replayed. It reports false if
+// there's none.
+func (r *replayer) lookup(cmdline ...string) (CapturedCommand, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, cmd := range r.commands {
+		if equalArgs(cmd.Args, cmdline) {
+			return cmd, true
+		}
+	}
+	return CapturedCommand{}, false
+}
+
+// run replays prog with args, parsing its recorded standard output with p.
+func (r *replayer) run(ctx context.Context, p parserFrom, prog string, args ...string) error {
+	cmd, ok := r.take(append([]string{prog}, args...))
+	if !ok {
+		return fmt.Errorf("replay %s '%v': command not recorded", prog, strings.Join(args, " "))
+	}
+
+	if cmd.ExitCode != 0 {
+		err := fmt.Errorf("replay %s '%v': %w", prog, strings.Join(args, " "),
+			&replayExitError{code: cmd.ExitCode, stderr: cmd.Stderr})
+		if code := runErrorCode(ctx, err, []byte(cmd.Stderr)); code != "" {
+			err = withCode(code, err)
+		}
+		return err
+	}
+
+	rbuf := strings.NewReader(cmd.Stdout)
+	var err error
+	if tp, ok := p.(truncatableParser); ok && cmd.Truncated {
+		err = tp.parseTruncatedFrom(rbuf)
+	} else {
+		err = p.parseFrom(rbuf)
+	}
+	if err != nil {
+		return withCode(CodeParseError, fmt.Errorf("replay %s '%v': %w", prog, strings.Join(args, " "), err))
+	}
+	return nil
+}
+
+func equalArgs(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,121 @@
+package gitstatus
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplay(t *testing.T) {
+	dir := t.TempDir()
+	synthRepo(t, dir, 20)
+	chdir(t, dir)
+
+	var dc DebugCapture
+	opts := []Option{WithUpstreamDiffStats(), WithDefaultBranchAheadCount(""), WithCommitsSinceTag()}
+	want, err := New(append(opts, WithDebugCapture(&dc))...)
+	require.NoError(t, err)
+
+	// Round-trip through JSON, as when sharing a capture.
+	buf, err := json.Marshal(&dc)
+	require.NoError(t, err)
+	var replayed DebugCapture
+	require.NoError(t, json.Unmarshal(buf, &replayed))
+	assert.Equal(t, dc.Commands(), replayed.Commands())
+
+	// Replay outside of the repository.
+	chdir(t, t.TempDir())
+	got, err := New(append(opts, WithReplay(&replayed))...)
+	require.NoError(t, err)
+	assert.Equal(t, exportedFields(want), exportedFields(got))
+
+	// Commands run by lazy mode methods are replayed too.
+	got, err = New(append(opts, WithReplay(&replayed), WithLazy())...)
+	require.NoError(t, err)
+	ins, del, err := got.DiffStats(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, want.Insertions, ins)
+	assert.Equal(t, want.Deletions, del)
+
+	// But each recorded command is only replayed once.
+	assert.Error(t, got.Refresh(context.Background()))
+}
+
+func TestReplayOutputLimit(t *testing.T) {
+	dir := t.TempDir()
+	synthRepo(t, dir, 20)
+	chdir(t, dir)
+	for i := 0; i < 20; i++ {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, fmt.Sprintf("untracked%02d", i)), nil, 0644))
+	}
+
+	var dc DebugCapture
+	want, err := New(WithOutputLimit(100), WithDebugCapture(&dc))
+	require.NoError(t, err)
+	require.True(t, want.Truncated)
+
+	// The truncated output is replayed as such.
+	chdir(t, t.TempDir())
+	got, err := New(WithOutputLimit(100), WithReplay(&dc))
+	require.NoError(t, err)
+	assert.Equal(t, exportedFields(want), exportedFields(got))
+}
+
+func TestReplayCachedVersion(t *testing.T) {
+	dir := t.TempDir()
+	synthRepo(t, dir, 1)
+	chdir(t, dir)
+
+	// The version detected by a previous call is recorded too.
+	_, err := GitVersion()
+	require.NoError(t, err)
+
+	var dc DebugCapture
+	_, err = New(WithDebugCapture(&dc))
+	require.NoError(t, err)
+	cmds := dc.Commands()
+	require.NotEmpty(t, cmds)
+	assert.Equal(t, []string{"git", "version"}, cmds[0].Args)
+
+	ver, err := GitVersion()
+	require.NoError(t, err)
+	var replayed Version
+	require.NoError(t, replayed.parseFrom(strings.NewReader(cmds[0].Stdout)))
+	assert.Equal(t, ver, replayed)
+}
+
+func TestReplayFailure(t *testing.T) {
+	var dc DebugCapture
+	dc.add(CapturedCommand{Args: []string{"git", "version"}, Stdout: "git version 2.39.2\n"})
+	dc.add(CapturedCommand{
+		Args:     []string{"git", "status", "--porcelain=v1", "--branch", "-z"},
+		ExitCode: 128,
+		Stderr:   "fatal: not a git repository (or any of the parent directories): .git\n",
+	})
+
+	_, err := New(WithReplay(&dc))
+	var gerr *Error
+	require.Truef(t, errors.As(err, &gerr), "got error %v (%T), want *Error", err, err)
+	assert.Equal(t, CodeNotARepository, gerr.Code)
+	assert.True(t, isExitError(err))
+
+	// With an older git, git status is run with other arguments, which
+	// haven't been recorded.
+	dc = DebugCapture{}
+	dc.add(CapturedCommand{Args: []string{"git", "version"}, Stdout: "git version 2.10.0\n"})
+	_, err = New(WithReplay(&dc))
+	assert.Error(t, err)
+	assert.False(t, isExitError(err))
+
+	// Nothing can be replayed from a nil capture.
+	_, err = New(WithReplay(nil))
+	assert.Error(t, err)
+}
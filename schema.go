@@ -0,0 +1,85 @@
+package gitstatus
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// JSONSchema returns a JSON Schema document describing the JSON encoding of
+// Status.
+//
+// The schema is generated from the Status struct definition so it always
+// reflects the fields returned by New.
+func JSONSchema() ([]byte, error) {
+	schema := map[string]any{
+		"$schema":              "https://json-schema.org/draft/2020-12/schema",
+		"title":                "Status",
+		"description":          "Status of a Git working tree directory.",
+		"type":                 "object",
+		"additionalProperties": false,
+	}
+
+	props := make(map[string]any)
+	var required []string
+	addStructProperties(reflect.TypeOf(Status{}), props, &required)
+
+	schema["properties"] = props
+	schema["required"] = required
+
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+var treeStateType = reflect.TypeOf(TreeState(0))
+
+// addStructProperties adds to props the JSON Schema of each exported field of
+// the struct type t, flattening embedded structs like encoding/json does.
+func addStructProperties(t reflect.Type, props map[string]any, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			addStructProperties(f.Type, props, required)
+			continue
+		}
+
+		props[f.Name] = typeSchema(f.Type)
+		*required = append(*required, f.Name)
+	}
+}
+
+// typeSchema returns the JSON Schema of the JSON encoding of a value of type t.
+func typeSchema(t reflect.Type) map[string]any {
+	if t == treeStateType {
+		// All the states known to TreeState.String.
+		var states []string
+		for ts := TreeState(0); ts < TreeState(len(_TreeState_index)-1); ts++ {
+			states = append(states, strings.ToLower(ts.String()))
+		}
+		return map[string]any{"type": "string", "enum": states}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": typeSchema(t.Elem())}
+	case reflect.Ptr:
+		return map[string]any{"anyOf": []any{typeSchema(t.Elem()), map[string]any{"type": "null"}}}
+	case reflect.Struct:
+		props := make(map[string]any)
+		var required []string
+		addStructProperties(t, props, &required)
+		return map[string]any{"type": "object", "properties": props, "required": required}
+	}
+	return map[string]any{}
+}
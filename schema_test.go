@@ -0,0 +1,51 @@
+package gitstatus
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/exp/maps"
+)
+
+func TestJSONSchemaInSync(t *testing.T) {
+	buf, err := JSONSchema()
+	require.NoError(t, err)
+
+	var schema struct {
+		Properties map[string]struct {
+			Type string   `json:"type"`
+			Enum []string `json:"enum"`
+		} `json:"properties"`
+		Required []string `json:"required"`
+	}
+	require.NoError(t, json.Unmarshal(buf, &schema))
+
+	// Encode a Status and check its keys are exactly the schema properties.
+	buf, err = json.Marshal(Status{State: Merging})
+	require.NoError(t, err)
+
+	var st map[string]any
+	require.NoError(t, json.Unmarshal(buf, &st))
+
+	assert.ElementsMatch(t, maps.Keys(st), maps.Keys(schema.Properties))
+	assert.ElementsMatch(t, maps.Keys(st), schema.Required)
+
+	for k, v := range st {
+		var typ string
+		switch v.(type) {
+		case bool:
+			typ = "boolean"
+		case float64:
+			typ = "integer"
+		case string:
+			typ = "string"
+		}
+		assert.Equalf(t, typ, schema.Properties[k].Type, "property %s", k)
+	}
+
+	assert.Contains(t, schema.Properties["State"].Enum, "merging")
+	assert.Len(t, schema.Properties["State"].Enum, len(_TreeState_index)-1)
+	assert.Contains(t, schema.Properties["State"].Enum, "bisecting")
+}
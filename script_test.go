@@ -1,15 +1,15 @@
-//go:build !windows
-// +build !windows
-
 package gitstatus
 
 import (
 	_ "embed"
+	"flag"
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
 	"reflect"
 	"regexp"
+	"strconv"
 	"strings"
 	"testing"
 
@@ -25,16 +25,138 @@ func TestScripts(t *testing.T) {
 	testscript.Run(t, testscript.Params{
 		Dir:      "testdata",
 		TestWork: true,
+		Cmds: map[string]func(ts *testscript.TestScript, neg bool, args []string){
+			"gitinit":    cmdGitInit,
+			"mkconflict": cmdMkConflict,
+			"mkdiverge":  cmdMkDiverge,
+			"mkstash":    cmdMkStash,
+		},
 	})
 }
 
+// git runs git with the given arguments in the current script directory and
+// returns its trimmed standard output, failing the script on error.
+func git(ts *testscript.TestScript, args ...string) string {
+	if err := ts.Exec("git", args...); err != nil {
+		ts.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, ts.ReadFile("stderr"))
+	}
+	return strings.TrimSpace(ts.ReadFile("stdout"))
+}
+
+// commit writes content into file then commits it with msg.
+func commit(ts *testscript.TestScript, file, content, msg string) {
+	ts.Check(os.WriteFile(ts.MkAbs(file), []byte(content), 0644))
+	git(ts, "add", file)
+	git(ts, "commit", "-q", "-m", msg)
+}
+
+// gitinit [branch]
+//
+// gitinit creates a git repository in the current directory, configures the
+// committer identity and checks out branch (main by default). Line endings
+// conversion is disabled so that scripts behave the same on all platforms.
+func cmdGitInit(ts *testscript.TestScript, neg bool, args []string) {
+	if neg || len(args) > 1 {
+		ts.Fatalf("usage: gitinit [branch]")
+	}
+	branch := "main"
+	if len(args) == 1 {
+		branch = args[0]
+	}
+
+	git(ts, "init", "-q")
+	git(ts, "config", "user.email", "i@example.com")
+	git(ts, "config", "user.name", "someone")
+	git(ts, "config", "core.autocrlf", "false")
+	git(ts, "checkout", "-q", "-b", branch)
+}
+
+// mkconflict file
+//
+// mkconflict merges into the current branch another branch modifying file in
+// a different way, leaving file in the unmerged, both modified, state (UU).
+func cmdMkConflict(ts *testscript.TestScript, neg bool, args []string) {
+	if neg || len(args) != 1 {
+		ts.Fatalf("usage: mkconflict file")
+	}
+	file := args[0]
+
+	commit(ts, file, "base\n", "mkconflict: base "+file)
+	git(ts, "checkout", "-q", "-b", "mkconflict-"+file)
+	commit(ts, file, "theirs\n", "mkconflict: theirs "+file)
+	git(ts, "checkout", "-q", "-")
+	commit(ts, file, "ours\n", "mkconflict: ours "+file)
+
+	if err := ts.Exec("git", "merge", "mkconflict-"+file); err == nil {
+		ts.Fatalf("mkconflict: merge unexpectedly succeeded")
+	}
+}
+
+// mkdiverge ahead behind
+//
+// mkdiverge pushes the current branch to a newly created 'origin' remote, and
+// sets it as its upstream branch. Then it creates empty commits so that the
+// local branch is ahead and behind its upstream by the given number of
+// commits. The working tree must be clean and contain at least one commit.
+func cmdMkDiverge(ts *testscript.TestScript, neg bool, args []string) {
+	if neg || len(args) != 2 {
+		ts.Fatalf("usage: mkdiverge ahead behind")
+	}
+	ahead, err := strconv.Atoi(args[0])
+	ts.Check(err)
+	behind, err := strconv.Atoi(args[1])
+	ts.Check(err)
+
+	branch := git(ts, "symbolic-ref", "--short", "HEAD")
+	// Keep the remote outside of the working tree.
+	dir, err := os.MkdirTemp("", "mkdiverge")
+	ts.Check(err)
+	ts.Defer(func() { os.RemoveAll(dir) })
+
+	remote := filepath.Join(dir, "origin.git")
+	git(ts, "init", "-q", "--bare", remote)
+	git(ts, "remote", "add", "origin", remote)
+
+	for i := 0; i < behind; i++ {
+		git(ts, "commit", "-q", "--allow-empty", "-m", fmt.Sprintf("mkdiverge: remote commit %d", i+1))
+	}
+	git(ts, "push", "-q", "--set-upstream", "origin", branch)
+	git(ts, "reset", "-q", "--hard", fmt.Sprintf("HEAD~%d", behind))
+
+	for i := 0; i < ahead; i++ {
+		git(ts, "commit", "-q", "--allow-empty", "-m", fmt.Sprintf("mkdiverge: local commit %d", i+1))
+	}
+}
+
+// mkstash msg
+//
+// mkstash creates a stash entry with the given message. The working tree must
+// contain at least one commit.
+func cmdMkStash(ts *testscript.TestScript, neg bool, args []string) {
+	if neg || len(args) != 1 {
+		ts.Fatalf("usage: mkstash msg")
+	}
+
+	const file = ".mkstash"
+	ts.Check(os.WriteFile(ts.MkAbs(file), []byte(args[0]+"\n"), 0644))
+	git(ts, "add", "-f", file)
+	git(ts, "stash", "push", "-q", "-m", args[0])
+}
+
 // gitstatus creates a Status object based on the current directory and compares
 // it with the git status representation in WANT_STATUS environment variable.
+// Command-line flags are converted into options passed to New.
 func gitstatus() int {
 	log.SetPrefix("Error(gitstatus): ")
 	log.SetFlags(0)
 
-	status, err := New()
+	opts, err := parseOptions(os.Args[1:])
+	if err != nil {
+		log.Print(err)
+		return 2
+	}
+
+	status, err := New(opts...)
 	if err != nil {
 		log.Printf("can't create Status object: %v", err)
 		return 1
@@ -69,6 +191,69 @@ func gitstatus() int {
 	return 0
 }
 
+// parseOptions converts gitstatus command-line flags into options for New.
+func parseOptions(args []string) ([]Option, error) {
+	fs := flag.NewFlagSet("gitstatus", flag.ContinueOnError)
+	untracked := fs.String("untracked", "", "untracked files mode: no, normal or all")
+	largeRepo := fs.Bool("large-repo", false, "use the large repository profile")
+	reducedStat := fs.Bool("reduced-stat", false, "reduce file system accesses")
+	upstreamStats := fs.Bool("upstream-stats", false, "compute diff stats with the upstream branch")
+	ignoreSubmodules := fs.String("ignore-submodules", "", "submodules changes to ignore: none, untracked, dirty or all")
+	divergenceFallback := fs.Bool("divergence-fallback", false, "compute the divergence with origin/<branch> if there's no upstream")
+	sinceTag := fs.Bool("since-tag", false, "compute the number of commits since the last tag")
+	outputLimit := fs.Int("output-limit", 0, "limit the git status output to this number of bytes")
+	indexFile := fs.String("index-file", "", "use this index file")
+	defaultBranch := fs.String("default-branch", "", "compute the ahead count with this default branch (. to detect it)")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	var opts []Option
+	if *largeRepo {
+		opts = append(opts, WithLargeRepoProfile())
+	}
+	if *reducedStat {
+		opts = append(opts, WithReducedStat())
+	}
+	if *upstreamStats {
+		opts = append(opts, WithUpstreamDiffStats())
+	}
+	if *indexFile != "" {
+		opts = append(opts, WithIndexFile(*indexFile))
+	}
+	if *outputLimit != 0 {
+		opts = append(opts, WithOutputLimit(*outputLimit))
+	}
+	if *sinceTag {
+		opts = append(opts, WithCommitsSinceTag())
+	}
+	if *divergenceFallback {
+		opts = append(opts, WithDivergenceFallback())
+	}
+	if *ignoreSubmodules != "" {
+		opts = append(opts, WithIgnoreSubmodules(SubmoduleIgnore(*ignoreSubmodules)))
+	}
+	switch *defaultBranch {
+	case "":
+	case ".":
+		opts = append(opts, WithDefaultBranchAheadCount(""))
+	default:
+		opts = append(opts, WithDefaultBranchAheadCount(*defaultBranch))
+	}
+	switch *untracked {
+	case "":
+	case "no":
+		opts = append(opts, WithUntrackedFiles(UntrackedNo))
+	case "normal":
+		opts = append(opts, WithUntrackedFiles(UntrackedNormal))
+	case "all":
+		opts = append(opts, WithUntrackedFiles(UntrackedAll))
+	default:
+		return nil, fmt.Errorf("invalid -untracked value: %q", *untracked)
+	}
+	return opts, nil
+}
+
 type fieldInfo struct {
 	name string
 	val  reflect.Value
@@ -83,6 +268,9 @@ func fieldInfos(s any) []fieldInfo {
 	iterFields = func(rv reflect.Value) {
 		for i := 0; i < rv.NumField(); i++ {
 			ftyp := rv.Type().Field(i)
+			if !ftyp.IsExported() {
+				continue
+			}
 			if ftyp.Type.Kind() == reflect.Struct {
 				iterFields(rv.Field(i))
 				continue
@@ -0,0 +1,60 @@
+package gitstatus
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+//go:generate go run golang.org/x/tools/cmd/stringer@latest -type=Severity -trimprefix=Severity
+
+// Severity is an overall classification of a Status, from the least to the
+// most in need of the user's attention. Formatters can use it to pick a
+// consistent color or alert level.
+type Severity int
+
+const (
+	// SeverityClean is the severity of a clean working tree, aligned with its
+	// upstream branch, if any.
+	SeverityClean Severity = iota
+
+	// SeverityDiverged is the severity of a clean working tree, with commits
+	// to push to, or pull from, its upstream branch.
+	SeverityDiverged
+
+	// SeverityDirty is the severity of a working tree having staged, modified
+	// or untracked files.
+	SeverityDirty
+
+	// SeverityOperationInProgress is the severity of a working tree in which
+	// an operation, such as a rebase or a merge, is in progress.
+	SeverityOperationInProgress
+
+	// SeverityConflicted is the severity of a working tree having unmerged
+	// files.
+	SeverityConflicted
+)
+
+// Severity returns the overall severity of the status. When several apply,
+// the highest one is returned, that is, by order of precedence:
+// SeverityConflicted, SeverityOperationInProgress, SeverityDirty,
+// SeverityDiverged and SeverityClean.
+//
+// In lazy mode, TreeState must have been called first.
+func (st *Status) Severity() Severity {
+	switch {
+	case st.HasConflicts():
+		return SeverityConflicted
+	case st.InProgressOperation():
+		return SeverityOperationInProgress
+	case st.NumStaged+st.NumModified+st.NumUntracked > 0:
+		return SeverityDirty
+	case st.AheadCount+st.BehindCount > 0:
+		return SeverityDiverged
+	}
+	return SeverityClean
+}
+
+// MarshalJSON returns the JSON encoding of the severity.
+func (s Severity) MarshalJSON() ([]byte, error) {
+	return json.Marshal(strings.ToLower(s.String()))
+}
@@ -0,0 +1,54 @@
+package gitstatus
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSeverity(t *testing.T) {
+	tests := []struct {
+		name string
+		st   Status
+		want Severity
+	}{
+		{
+			name: "clean",
+			st:   Status{Porcelain: Porcelain{LocalBranch: "main", RemoteBranch: "origin/main"}, IsClean: true},
+			want: SeverityClean,
+		},
+		{
+			name: "diverged",
+			st:   Status{Porcelain: Porcelain{LocalBranch: "main", RemoteBranch: "origin/main", BehindCount: 1}, IsClean: true},
+			want: SeverityDiverged,
+		},
+		{
+			name: "dirty and diverged",
+			st:   Status{Porcelain: Porcelain{LocalBranch: "main", RemoteBranch: "origin/main", AheadCount: 1, NumUntracked: 1}},
+			want: SeverityDirty,
+		},
+		{
+			name: "dirty during bisect",
+			st:   Status{Porcelain: Porcelain{IsDetached: true, NumModified: 1}, State: Bisecting},
+			want: SeverityOperationInProgress,
+		},
+		{
+			name: "conflicted merge",
+			st:   Status{Porcelain: Porcelain{LocalBranch: "main", NumConflicts: 1, NumStaged: 2}, State: Merging},
+			want: SeverityConflicted,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.st.Severity())
+		})
+	}
+}
+
+func TestSeverityJSON(t *testing.T) {
+	buf, err := json.Marshal(SeverityOperationInProgress)
+	assert.NoError(t, err)
+	assert.Equal(t, `"operationinprogress"`, string(buf))
+}
@@ -0,0 +1,7 @@
+//go:build !aix && !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !solaris
+
+package gitstatus
+
+import "os"
+
+func inode(fi os.FileInfo) uint64 { return 0 }
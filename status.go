@@ -10,7 +10,7 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
-	"regexp"
+	"path/filepath"
 	"strconv"
 	"strings"
 )
@@ -25,6 +25,17 @@ type Status struct {
 	// HEAD is the shortened SHA1 of current commit (empty in initial state).
 	HEAD string
 
+	// HEADFull is the full SHA1 of current commit (empty in initial state).
+	HEADFull string
+
+	// GitDir is the absolute path of the git directory (empty in initial
+	// state).
+	GitDir string
+
+	// TopLevel is the absolute path of the top-level directory of the working
+	// tree (empty in initial state).
+	TopLevel string
+
 	// State indicates the state of the working tree.
 	State TreeState
 
@@ -37,6 +48,44 @@ type Status struct {
 
 	// Deletions is the count of deleted lines in the staging area.
 	Deletions int
+
+	// UpstreamInsertions is the count of lines inserted by the commits of the
+	// local branch not yet in its upstream branch. It's only computed when
+	// using WithUpstreamDiffStats.
+	UpstreamInsertions int
+
+	// UpstreamDeletions is the count of lines deleted by the commits of the
+	// local branch not yet in its upstream branch. It's only computed when
+	// using WithUpstreamDiffStats.
+	UpstreamDeletions int
+
+	// DefaultBranch is the default branch of the repository, against which
+	// DefaultBranchAheadCount is computed. It's only set when using
+	// WithDefaultBranchAheadCount.
+	DefaultBranch string
+
+	// DefaultBranchAheadCount is the number of commits of HEAD not contained
+	// in the default branch. It's only computed when using
+	// WithDefaultBranchAheadCount.
+	DefaultBranchAheadCount int
+
+	// RemoteBranchGuessed reports whether RemoteBranch, AheadCount and
+	// BehindCount are relative to the branch of the same name on the origin
+	// remote, because the local branch has no upstream branch. It's only set
+	// when using WithDivergenceFallback.
+	RemoteBranchGuessed bool
+
+	// LastTag is the most recent tag reachable from HEAD. It's only set when
+	// using WithCommitsSinceTag.
+	LastTag string
+
+	// CommitsSinceTag is the number of commits made since LastTag. It's only
+	// computed when using WithCommitsSinceTag.
+	CommitsSinceTag int
+
+	opts    *options
+	pending pendingFields // fields not computed yet, in lazy mode
+	refs    refsStamp     // state of the refs when the status was computed
 }
 
 // Porcelain holds the Git status variables extracted from calling git status --porcelain.
@@ -67,11 +116,20 @@ type Porcelain struct {
 	// RemoteBranch is the name of upstream remote branch (tracking).
 	RemoteBranch string
 
+	// UpstreamGone reports whether the upstream remote branch is configured
+	// but doesn't exist anymore.
+	UpstreamGone bool
+
 	// AheadCount reports by how many commits the local branch is ahead of its upstream branch.
 	AheadCount int
 
 	// BehindCount reports by how many commits the local branch is behind its upstream branch.
 	BehindCount int
+
+	// Truncated reports whether the output of git status has been truncated
+	// because of WithOutputLimit, in which case the file counts are lower
+	// bounds.
+	Truncated bool
 }
 
 var (
@@ -80,58 +138,191 @@ var (
 )
 
 // New returns the Git Status of the current working directory.
-func New() (*Status, error) { return newStatus(context.Background()) }
+func New(opts ...Option) (*Status, error) { return newStatus(context.Background(), opts) }
 
 // NewWithContext is likes New but includes a context.
 //
 // The provided context is used to stop retrieving git status if the context
 // becomes done before all calls to git have completed.
-func NewWithContext(ctx context.Context) (*Status, error) { return newStatus(ctx) }
+func NewWithContext(ctx context.Context, opts ...Option) (*Status, error) {
+	return newStatus(ctx, opts)
+}
 
-func newStatus(ctx context.Context) (*Status, error) {
-	por := Porcelain{}
-	err := runAndParse(ctx, &por, "git", "status", "--porcelain=v1", "--branch", "-z")
-	if err != nil {
+func newStatus(ctx context.Context, opts []Option) (*Status, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if err := o.checkRepository(); err != nil {
 		return nil, err
 	}
 
-	stats := stats{}
-	err = runAndParse(ctx, &stats, "git", "diff", "--shortstat")
-	if err != nil {
+	st := &Status{opts: &o}
+	if err := st.update(ctx); err != nil {
 		return nil, err
 	}
+	return st, nil
+}
+
+// update computes the status, reusing the values of st which can't have
+// changed since st was last computed. st is only modified on success.
+func (st *Status) update(ctx context.Context) error {
+	o := st.opts
+	if o == nil {
+		o = new(options)
+	}
+	ver, err := detectGitVersion(ctx, o)
+	if err != nil {
+		return err
+	}
+
+	if o.fetch {
+		if err := o.runFetch(ctx, ver); err != nil {
+			return err
+		}
+	}
+
+	porcelainFlag := "--porcelain=v1"
+	if !ver.supports(featPorcelainV1) {
+		porcelainFlag = "--porcelain"
+	}
+
+	args := []string{"status", porcelainFlag, "--branch", "-z"}
+	if flag := o.untracked.flag(); flag != "" {
+		args = append(args, flag)
+	}
+	if flag := o.ignoreSubmodules.flag(); flag != "" {
+		args = append(args, flag)
+	}
+	if o.noAheadBehind && ver.supports(featNoAheadBehind) {
+		args = append(args, "--no-ahead-behind")
+	}
+
+	por := Porcelain{}
+	err = o.runAndParse(ctx, &por, "git", args...)
+	if err != nil {
+		return err
+	}
+
+	next := Status{Porcelain: por, opts: o}
 
 	// All successive commands require at least one commit.
 	if por.IsInitial {
-		return &Status{Porcelain: por}, nil
+		*st = next
+		return nil
 	}
 
-	// Count stash entries.
-	nstashed := linecount(0)
-	if err = runAndParse(ctx, &nstashed, "git", "stash", "list"); err != nil {
-		return nil, err
+	// Stamp the refs before running the commands depending on them, so that
+	// changes made in the meantime are seen by the next update. The first
+	// time, the git directory isn't known yet and is looked for, then checked
+	// against that reported by git.
+	var (
+		refs           refsStamp
+		stampedDir     string
+		headUnchanged  bool
+		stashUnchanged bool
+	)
+	if st.GitDir != "" {
+		refs = stampRefs(st.GitDir)
+		headUnchanged = refs.valid && st.refs.valid && refs.head == st.refs.head
+		stashUnchanged = refs.valid && st.refs.valid && refs.stash == st.refs.stash &&
+			st.pending&pendingStashCount == 0
+	} else if stampedDir = o.discoverGitDir(); stampedDir != "" {
+		refs = stampRefs(stampedDir)
 	}
 
-	// Sets other special flags and fields.
-	var lines lines
-	err = runAndParse(ctx, &lines, "git", "rev-parse", "--git-dir", "--short", "HEAD")
-	if err != nil || len(lines) != 2 {
-		return nil, err
+	// Without modified nor staged files, there's no diff to compute.
+	diffStats := !o.noDiffStats && por.NumModified+por.NumConflicts+por.NumStaged != 0
+
+	if o.divergenceFallback && !o.noAheadBehind && por.RemoteBranch == "" && !por.IsDetached {
+		if err := next.computeGuessedDivergence(ctx); err != nil {
+			return err
+		}
 	}
 
-	isClean := por.NumStaged+por.NumConflicts+por.NumModified+por.NumUntracked == 0
+	// Diff stats with the upstream branch require an upstream branch.
+	upstreamStats := o.upstreamStats && por.RemoteBranch != "" && !por.UpstreamGone
 
-	st := &Status{
-		Porcelain:  por,
-		State:      treeStateFromDir(strings.TrimSpace(lines[0])),
-		HEAD:       strings.TrimSpace(lines[1]),
-		NumStashed: int(nstashed),
-		IsClean:    isClean,
-		Insertions: stats.insertions,
-		Deletions:  stats.deletions,
+	if stashUnchanged {
+		next.NumStashed = st.NumStashed
 	}
 
-	return st, nil
+	if o.lazy {
+		next.pending = pendingTreeState
+		if !stashUnchanged {
+			next.pending |= pendingStashCount
+		}
+		if diffStats {
+			next.pending |= pendingDiffStats
+		}
+		if upstreamStats {
+			next.pending |= pendingUpstreamDiffStats
+		}
+	} else {
+		if diffStats {
+			if err := next.computeDiffStats(ctx); err != nil {
+				return err
+			}
+		}
+		if upstreamStats {
+			if err := next.computeUpstreamDiffStats(ctx); err != nil {
+				return err
+			}
+		}
+		if !stashUnchanged {
+			if err := next.computeStashCount(ctx); err != nil {
+				return err
+			}
+		}
+	}
+
+	if headUnchanged {
+		next.HEAD, next.HEADFull = st.HEAD, st.HEADFull
+		next.GitDir, next.TopLevel = st.GitDir, st.TopLevel
+	} else {
+		// Sets other special flags and fields, with a single call to rev-parse.
+		gitDirFlag := "--absolute-git-dir"
+		if !ver.supports(featAbsoluteGitDir) {
+			gitDirFlag = "--git-dir"
+		}
+		rp := revParse{dir: o.dir}
+		err = o.runAndParse(ctx, &rp, "git", "rev-parse", gitDirFlag, "--show-toplevel", "HEAD", "--short", "HEAD")
+		if err != nil {
+			return err
+		}
+
+		next.HEAD = rp.shortHEAD
+		next.HEADFull = rp.head
+		next.GitDir = rp.gitDir
+		next.TopLevel = rp.topLevel
+	}
+
+	if st.GitDir == "" && !sameFile(stampedDir, next.GitDir) {
+		refs = refsStamp{}
+	}
+	next.refs = refs
+
+	// A truncated output has entries beyond the limit.
+	next.IsClean = !por.Truncated && por.NumStaged+por.NumConflicts+por.NumModified+por.NumUntracked == 0
+	if !o.lazy {
+		next.State = treeStateFromDir(next.GitDir)
+	}
+
+	if o.defaultBranchEnabled {
+		if err := next.computeDefaultBranchAheadCount(ctx); err != nil {
+			return err
+		}
+	}
+
+	if o.commitsSinceTag {
+		if err := next.computeCommitsSinceTag(ctx); err != nil {
+			return err
+		}
+	}
+
+	*st = next
+	return nil
 }
 
 // scanNilBytes is a bufio.SplitFunc function used to tokenize the input with
@@ -156,17 +347,37 @@ func scanNilBytes(data []byte, atEOF bool) (advance int, token []byte, err error
 	return 0, nil, nil
 }
 
-var fileStatusRx = regexp.MustCompile(`^(##|[ MADRCUT?!]{2}) .*$`)
+// isStatusLine reports whether line starts with either the branch header
+// marker (##) or a valid XY status code, followed by a space.
+func isStatusLine(line []byte) bool {
+	if len(line) < 3 || line[2] != ' ' {
+		return false
+	}
+	if line[0] == '#' && line[1] == '#' {
+		return true
+	}
+	return isStatusCode(line[0]) && isStatusCode(line[1])
+}
+
+// isStatusCode reports whether c is one of the X or Y status codes of a git
+// status porcelain entry.
+func isStatusCode(c byte) bool {
+	switch c {
+	case ' ', 'M', 'A', 'D', 'R', 'C', 'U', 'T', '?', '!':
+		return true
+	}
+	return false
+}
 
 // parseStatus parses porcelain status and fills it with r.
 func (p *Porcelain) parseFrom(r io.Reader) error {
-	scan := bufio.NewScanner(r)
-	scan.Split(scanNilBytes)
+	scan, release := newScanner(r, scanNilBytes)
+	defer release()
 
 	var err error
 	for scan.Scan() {
-		line := scan.Text()
-		if !fileStatusRx.MatchString(line) {
+		line := scan.Bytes()
+		if !isStatusLine(line) {
 			continue
 		}
 
@@ -174,7 +385,7 @@ func (p *Porcelain) parseFrom(r io.Reader) error {
 
 		switch {
 		case first == '#' && second == '#':
-			err = p.parseHeader(line)
+			err = p.parseHeader(string(line))
 		case first == 'U', second == 'U',
 			first == 'A' && second == 'A':
 			p.NumConflicts++
@@ -202,10 +413,23 @@ func (p *Porcelain) parseFrom(r io.Reader) error {
 	return scan.Err()
 }
 
+// parseTruncatedFrom parses the porcelain status read from r, truncated after
+// its first bytes. The last, incomplete, entry is ignored.
+func (p *Porcelain) parseTruncatedFrom(r io.Reader) error {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	p.Truncated = true
+	return p.parseFrom(bytes.NewReader(b[:bytes.LastIndexByte(b, 0)+1]))
+}
+
 func (p *Porcelain) parseHeader(line string) error {
 	const (
-		initialPrefix = "## No commits yet on "
-		detachedStr   = "## HEAD (no branch)"
+		initialPrefix    = "## No commits yet on "
+		oldInitialPrefix = "## Initial commit on " // before git 2.15
+		detachedStr      = "## HEAD (no branch)"
 	)
 
 	switch {
@@ -214,24 +438,28 @@ func (p *Porcelain) parseHeader(line string) error {
 	case strings.HasPrefix(line, initialPrefix):
 		p.IsInitial = true
 		p.LocalBranch = line[len(initialPrefix):]
+	case strings.HasPrefix(line, oldInitialPrefix):
+		p.IsInitial = true
+		p.LocalBranch = line[len(oldInitialPrefix):]
 	default:
 		// regular branch[...remote] output, with or without ahead/behind counts
 		if len(line) < 4 {
 			// branch name is at least one character
 			return errUnexpectedHeader
 		}
+		line = line[3:]
 		// check if a remote tracking branch is specified
 		pos := strings.Index(line, "...")
 		if pos == -1 {
 			// we should have the branch name and nothing else, where spaces
 			// are not allowed
-			if strings.IndexByte(line[3:], ' ') != -1 {
+			if strings.IndexByte(line, ' ') != -1 {
 				return errUnexpectedHeader
 			}
-			p.LocalBranch = line[3:]
+			p.LocalBranch = line
 		} else {
-			p.LocalBranch = line[3:pos]
-			p.parseUpstream(line[pos+3:])
+			p.LocalBranch = line[:pos]
+			return p.parseUpstream(line[pos+3:])
 		}
 	}
 
@@ -241,42 +469,67 @@ func (p *Porcelain) parseHeader(line string) error {
 // parseUpstream parses the remote branch name and if present, its divergence
 // with local branch (ahead / behind count)
 func (p *Porcelain) parseUpstream(s string) error {
-	var err error
-
 	pos := strings.IndexByte(s, ' ')
 	if pos == -1 {
 		p.RemoteBranch = s
 		return nil
 	}
 	p.RemoteBranch = s[:pos]
-	s = strings.Trim(s[pos+1:], "[]")
+
+	track := s[pos+1:]
+	if track == "[gone]" {
+		p.UpstreamGone = true
+		return nil
+	}
+
+	var err error
+	p.AheadCount, p.BehindCount, err = parseAheadBehind(track)
+	return err
+}
+
+// parseAheadBehind parses the divergence between a local branch and its
+// upstream, as found in the bracketed part of git status branch header, or in
+// the output of %(upstream:track) in git for-each-ref.
+func parseAheadBehind(s string) (ahead, behind int, err error) {
+	s = strings.Trim(s, "[]")
+	switch s {
+	case "gone":
+		// The upstream branch doesn't exist anymore.
+		return 0, 0, nil
+	case "different":
+		// Branches differ, but counts haven't been computed (--no-ahead-behind).
+		return 0, 0, nil
+	}
 
 	hasAhead := strings.Contains(s, "ahead")
 	hasBehind := strings.Contains(s, "behind")
 
 	switch {
 	case hasAhead && hasBehind:
-		_, err = fmt.Sscanf(s, "ahead %d, behind %d", &p.AheadCount, &p.BehindCount)
+		_, err = fmt.Sscanf(s, "ahead %d, behind %d", &ahead, &behind)
 	case hasAhead:
-		_, err = fmt.Sscanf(s, "ahead %d", &p.AheadCount)
+		_, err = fmt.Sscanf(s, "ahead %d", &ahead)
 	case hasBehind:
-		_, err = fmt.Sscanf(s, "behind %d", &p.BehindCount)
+		_, err = fmt.Sscanf(s, "behind %d", &behind)
 	default:
 		err = fmt.Errorf(`unexpected string "%s"`, s)
 	}
 
+	if err == nil && (ahead < 0 || behind < 0) {
+		err = fmt.Errorf(`negative count in "%s"`, s)
+	}
 	if err != nil {
-		return fmt.Errorf("%v: %w", errParseAheadBehind, err)
+		return 0, 0, fmt.Errorf("%v: %w", errParseAheadBehind, err)
 	}
-	return nil
+	return ahead, behind, nil
 }
 
 type linecount int
 
 // parseFrom counts the number of lines by reading from r.
 func (lc *linecount) parseFrom(r io.Reader) error {
-	scan := bufio.NewScanner(r)
-	scan.Split(bufio.ScanLines)
+	scan, release := newScanner(r, bufio.ScanLines)
+	defer release()
 
 	for scan.Scan() {
 		*lc++
@@ -289,8 +542,8 @@ type lines []string
 
 // parseFrom appends to itself the lines it finds by reading r.
 func (l *lines) parseFrom(r io.Reader) error {
-	scan := bufio.NewScanner(r)
-	scan.Split(bufio.ScanLines)
+	scan, release := newScanner(r, bufio.ScanLines)
+	defer release()
 
 	for scan.Scan() {
 		*l = append(*l, scan.Text())
@@ -299,38 +552,95 @@ func (l *lines) parseFrom(r io.Reader) error {
 	return scan.Err()
 }
 
-type stats struct {
-	insertions int
-	deletions  int
+// revParse holds the output of:
+//
+//	git rev-parse --absolute-git-dir --show-toplevel HEAD --short HEAD
+//
+// dir is the directory git runs in, against which a relative git directory,
+// printed by versions of git lacking --absolute-git-dir, is resolved.
+type revParse struct {
+	dir       string
+	gitDir    string
+	topLevel  string
+	head      string
+	shortHEAD string
 }
 
-// parseStatus parses porcelain status and fills it with r.
-func (s *stats) parseFrom(r io.Reader) error {
-	b, err := ioutil.ReadAll(r)
+// parseFrom parses the output of git rev-parse from r.
+func (rp *revParse) parseFrom(r io.Reader) error {
+	var l lines
+	if err := l.parseFrom(r); err != nil {
+		return err
+	}
+	if len(l) != 4 {
+		return fmt.Errorf("unexpected rev-parse output: got %d lines, want 4", len(l))
+	}
+
+	gitDir := strings.TrimSpace(l[0])
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(rp.dir, gitDir)
+	}
+	gitDir, err := filepath.Abs(gitDir)
 	if err != nil {
 		return err
 	}
 
-	s.insertions, s.deletions, err = extractShortStat(b)
-	return err
+	*rp = revParse{
+		dir:       rp.dir,
+		gitDir:    gitDir,
+		topLevel:  strings.TrimSpace(l[1]),
+		head:      strings.TrimSpace(l[2]),
+		shortHEAD: strings.TrimSpace(l[3]),
+	}
+	return nil
+}
+
+type stats struct {
+	insertions int
+	deletions  int
 }
 
-func extractShortStat(out []byte) (insertions, deletions int, err error) {
-	splits := bytes.Split(out, []byte{','})
-	for j := range splits {
-		line := bytes.TrimSpace(splits[j])
-		if pos := bytes.Index(line, []byte("insertion")); pos != -1 {
-			insertions, err = strconv.Atoi(string(bytes.TrimSpace(line[:pos])))
-			if err != nil {
-				return
+// parseFrom parses the output of git diff --numstat -z from r, summing the
+// inserted and deleted lines of all files.
+func (s *stats) parseFrom(r io.Reader) error {
+	scan, release := newScanner(r, scanNilBytes)
+	defer release()
+
+	for scan.Scan() {
+		// Each entry is made of the number of added and deleted lines, and
+		// the path, separated by tabs.
+		fields := bytes.SplitN(scan.Bytes(), []byte{'\t'}, 3)
+		if len(fields) != 3 {
+			return fmt.Errorf("unexpected numstat entry %q", scan.Text())
+		}
+
+		// Binary files show '-' instead of counts.
+		binary := string(fields[0]) == "-" && string(fields[1]) == "-"
+
+		var ins, del int
+		if !binary {
+			var err1, err2 error
+			ins, err1 = strconv.Atoi(string(fields[0]))
+			del, err2 = strconv.Atoi(string(fields[1]))
+			if err1 != nil || err2 != nil || ins < 0 || del < 0 {
+				return fmt.Errorf("unexpected numstat entry %q", scan.Text())
 			}
-		} else if pos := bytes.Index(line, []byte("deletion")); pos != -1 {
-			deletions, err = strconv.Atoi(string(bytes.TrimSpace(line[:pos])))
-			if err != nil {
-				return
+		}
+
+		if len(fields[2]) == 0 {
+			// Renames and copies have an empty path, followed by the source
+			// and destination paths.
+			if !scan.Scan() || !scan.Scan() {
+				if err := scan.Err(); err != nil {
+					return err
+				}
+				return errors.New("truncated numstat rename entry")
 			}
 		}
+
+		s.insertions += ins
+		s.deletions += del
 	}
 
-	return insertions, deletions, err
+	return scan.Err()
 }
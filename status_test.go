@@ -2,12 +2,16 @@ package gitstatus
 
 import (
 	"bytes"
+	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 )
 
+// Status values must remain comparable.
+var _ = Status{} == Status{}
+
 func porcelainNZT(lines ...string) []byte {
 	return append([]byte(strings.Join(lines, "\x00")), 0)
 }
@@ -73,6 +77,27 @@ func TestStatusParseHeaders(t *testing.T) {
 				BehindCount:  2,
 			},
 		},
+		{
+			name: "upstream gone",
+			out: porcelainNZT(
+				"## feature/123/a...upstream/feature/123/a [gone]",
+			),
+			want: Porcelain{
+				LocalBranch:  "feature/123/a",
+				RemoteBranch: "upstream/feature/123/a",
+				UpstreamGone: true,
+			},
+		},
+		{
+			name: "no ahead behind",
+			out: porcelainNZT(
+				"## feature/123/a...upstream/feature/123/a [different]",
+			),
+			want: Porcelain{
+				LocalBranch:  "feature/123/a",
+				RemoteBranch: "upstream/feature/123/a",
+			},
+		},
 		{
 			name: "initial",
 			out: porcelainNZT(
@@ -83,6 +108,16 @@ func TestStatusParseHeaders(t *testing.T) {
 				IsInitial:   true,
 			},
 		},
+		{
+			name: "initial (git < 2.15)",
+			out: porcelainNZT(
+				"## Initial commit on thisbranch",
+			),
+			want: Porcelain{
+				LocalBranch: "thisbranch",
+				IsInitial:   true,
+			},
+		},
 		{
 			name: "detached",
 			out: porcelainNZT(
@@ -288,6 +323,8 @@ func TestStatusParseMalformed(t *testing.T) {
 		{name: "illformed header", out: porcelainNZT(`## branch [ahead 2`)},
 		{name: "illformed header", out: porcelainNZT(`## branch [ahead 2,`)},
 		{name: "illformed header", out: porcelainNZT(`## branch [ahead 2, behind 3`)},
+		{name: "illformed upstream", out: porcelainNZT(`## branch...origin/branch [ahead]`)},
+		{name: "negative count", out: porcelainNZT(`## branch...origin/branch [behind -2]`)},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -317,30 +354,180 @@ func TestLineCount(t *testing.T) {
 	}
 }
 
-func Test_extractShortStat(t *testing.T) {
+func TestStatsParse(t *testing.T) {
 	tests := []struct {
-		line       string
+		name       string
+		out        []byte // git diff --numstat -z output
 		insertions int
 		deletions  int
+		wantErr    bool
 	}{
-		{line: `2 files changed, 55 insertions(+), 1 deletion(-)`, insertions: 55, deletions: 1},
-		{line: `2 files changed, 55 insertions(+), 3 deletions(-)`, insertions: 55, deletions: 3},
-		{line: `2 files changed, 1 insertion(+), 1 deletion(-)`, insertions: 1, deletions: 1},
-		{line: `2 files changed, 1 insertion(+), 23 deletions(-)`, insertions: 1, deletions: 23},
-		{line: `1 file changed, 14 insertions(+)`, insertions: 14},
-		{line: `1 file changed, 1 insertion(+)`, insertions: 1},
-		{line: `1 file changed, 53 deletions(+)`, deletions: 53},
-		{line: `1 file changed, 1 deletion(+)`, deletions: 1},
-		{line: ``, insertions: 0, deletions: 0},
+		{name: "empty", out: nil},
+		{
+			name:       "single file",
+			out:        porcelainNZT("55\t1\tfile"),
+			insertions: 55, deletions: 1,
+		},
+		{
+			name:       "multiple files",
+			out:        porcelainNZT("55\t3\tfile", "0\t23\tdir/file with spaces", "14\t0\tdir/file\twith\ttabs"),
+			insertions: 69, deletions: 26,
+		},
+		{
+			name:       "binary and renamed",
+			out:        porcelainNZT("-\t-\tbin", "2\t1\t", "old", "new", "1\t0\tfile"),
+			insertions: 3, deletions: 1,
+		},
+		{name: "truncated rename", out: porcelainNZT("2\t1\t", "old"), wantErr: true},
+		{name: "missing path", out: porcelainNZT("2\t1"), wantErr: true},
+		{name: "negative count", out: porcelainNZT("-2\t1\tfile"), wantErr: true},
+		{name: "not a number", out: porcelainNZT("2\tx\tfile"), wantErr: true},
+		{name: "no trailing nil byte", out: []byte("2\t1\tfile"), wantErr: true},
 	}
 
 	for _, tt := range tests {
-		insertions, deletions, err := extractShortStat([]byte(tt.line))
-		if err != nil {
-			t.Fatal(err)
+		t.Run(tt.name, func(t *testing.T) {
+			var s stats
+			err := s.parseFrom(bytes.NewReader(tt.out))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, stats{insertions: tt.insertions, deletions: tt.deletions}, s)
+		})
+	}
+}
+
+func FuzzPorcelainParse(f *testing.F) {
+	f.Add(porcelainNZT("## master...origin/master [ahead 1, behind 2]", " M file", "?? untracked"))
+	f.Add(porcelainNZT("## No commits yet on main", "A  file"))
+	f.Add(porcelainNZT("## HEAD (no branch)", "UU file", "R  old -> new"))
+	f.Add(porcelainNZT("## feature/123/a...upstream/feature/123/a [gone]"))
+	f.Add(porcelainNZT("## a...b [ahead"))
+	f.Add([]byte("## master"))
+
+	f.Fuzz(func(t *testing.T, out []byte) {
+		var p Porcelain
+		if err := p.parseFrom(bytes.NewReader(out)); err != nil {
+			return
+		}
+		if p.NumModified < 0 || p.NumConflicts < 0 || p.NumUntracked < 0 || p.NumStaged < 0 {
+			t.Errorf("negative file count: %+v", p)
 		}
-		if tt.insertions != insertions || tt.deletions != deletions {
-			t.Errorf("git diff --shortstat\n%s\n got +%d/-%d want +%d/-%d", tt.line, insertions, deletions, tt.insertions, tt.deletions)
+		if p.AheadCount < 0 || p.BehindCount < 0 {
+			t.Errorf("negative divergence: %+v", p)
+		}
+	})
+}
+
+func FuzzParseHeader(f *testing.F) {
+	f.Add("master")
+	f.Add("master...origin/master")
+	f.Add("feature/123/a...upstream/feature/123/a [ahead 26, behind 2]")
+	f.Add("No commits yet on thisbranch")
+	f.Add("HEAD (no branch)")
+	f.Add("...")
+	f.Add("")
+
+	f.Fuzz(func(t *testing.T, s string) {
+		var p Porcelain
+		if err := p.parseHeader("## " + s); err != nil {
+			return
 		}
+		if p.AheadCount < 0 || p.BehindCount < 0 {
+			t.Errorf("negative divergence: %+v", p)
+		}
+	})
+}
+
+func FuzzParseUpstream(f *testing.F) {
+	f.Add("origin/master")
+	f.Add("origin/master [ahead 3]")
+	f.Add("origin/master [behind 2]")
+	f.Add("origin/master [ahead 26, behind 2]")
+	f.Add("origin/master [gone]")
+	f.Add("origin/master [ahead -1]")
+	f.Add(" ")
+
+	f.Fuzz(func(t *testing.T, s string) {
+		var p Porcelain
+		if err := p.parseUpstream(s); err != nil {
+			return
+		}
+		if p.AheadCount < 0 || p.BehindCount < 0 {
+			t.Errorf("parseUpstream(%q): negative divergence: %+v", s, p)
+		}
+		if strings.ContainsRune(p.RemoteBranch, ' ') {
+			t.Errorf("parseUpstream(%q): remote branch contains a space: %q", s, p.RemoteBranch)
+		}
+	})
+}
+
+func FuzzStatsParse(f *testing.F) {
+	f.Add(porcelainNZT("55\t1\tfile"))
+	f.Add(porcelainNZT("-\t-\tbin", "2\t1\t", "old", "new"))
+	f.Add(porcelainNZT("2\t1\t"))
+	f.Add([]byte(``))
+
+	f.Fuzz(func(t *testing.T, out []byte) {
+		var s stats
+		if err := s.parseFrom(bytes.NewReader(out)); err != nil {
+			return
+		}
+		if s.insertions < 0 || s.deletions < 0 {
+			t.Errorf("negative stats: %+v", s)
+		}
+	})
+}
+
+func TestIsStatusLine(t *testing.T) {
+	tests := []struct {
+		line string
+		want bool
+	}{
+		{line: "## main", want: true},
+		{line: " M file", want: true},
+		{line: "MM file", want: true},
+		{line: "?? file", want: true},
+		{line: "!! file", want: true},
+		{line: "R  new", want: true},
+		{line: "?? dir/with a\nnewline", want: true},
+		{line: "## ", want: true},
+		{line: "##", want: false},
+		{line: "", want: false},
+		{line: "M", want: false},
+		{line: "MMfile", want: false},
+		{line: "XY file", want: false},
+		{line: "#M file", want: false},
 	}
+	for _, tt := range tests {
+		if got := isStatusLine([]byte(tt.line)); got != tt.want {
+			t.Errorf("isStatusLine(%q) = %t, want %t", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestRevParse(t *testing.T) {
+	gitDir := filepath.Join(t.TempDir(), ".git")
+
+	var rp revParse
+	err := rp.parseFrom(strings.NewReader(gitDir + "\n/repo\n0123456789abcdef0123456789abcdef01234567\n0123456\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, revParse{
+		gitDir:    gitDir,
+		topLevel:  "/repo",
+		head:      "0123456789abcdef0123456789abcdef01234567",
+		shortHEAD: "0123456",
+	}, rp)
+
+	assert.Error(t, rp.parseFrom(strings.NewReader(gitDir+"\n0123456\n")))
+
+	// A relative git directory is relative to the directory git ran in, not
+	// to the current directory.
+	dir := t.TempDir()
+	rp = revParse{dir: dir}
+	err = rp.parseFrom(strings.NewReader(".git\n/repo\n0123456789abcdef0123456789abcdef01234567\n0123456\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, ".git"), rp.gitDir)
 }
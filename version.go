@@ -0,0 +1,182 @@
+package gitstatus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+)
+
+// Version is the version of a git executable.
+type Version struct {
+	Major, Minor, Patch int
+}
+
+// String returns the version in the major.minor.patch format.
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// AtLeast reports whether v is the same or a more recent version than
+// major.minor.patch.
+func (v Version) AtLeast(major, minor, patch int) bool {
+	if v.Major != major {
+		return v.Major > major
+	}
+	if v.Minor != minor {
+		return v.Minor > minor
+	}
+	return v.Patch >= patch
+}
+
+// minVersion is the oldest version of git supported by this package, in which
+// git status supports --porcelain, --branch and -z.
+var minVersion = Version{1, 8, 0}
+
+// feature is a feature of git used by this package when available, that is,
+// if git is more recent than minVersion.
+type feature int
+
+const (
+	featPorcelainV1            feature = iota // git status --porcelain=v1
+	featAbsoluteGitDir                        // git rev-parse --absolute-git-dir
+	featNoAheadBehind                         // git status --no-ahead-behind
+	featWorktreeList                          // git worktree list
+	featFetchNoAutoGC                         // git fetch --no-auto-gc
+	featFetchNoAutoMaintenance                // git fetch --no-auto-maintenance
+)
+
+// featureVersions are the versions of git in which each feature appeared.
+var featureVersions = [...]Version{
+	featPorcelainV1:            {2, 11, 0},
+	featAbsoluteGitDir:         {2, 13, 0},
+	featNoAheadBehind:          {2, 17, 0},
+	featWorktreeList:           {2, 7, 0},
+	featFetchNoAutoGC:          {2, 23, 0},
+	featFetchNoAutoMaintenance: {2, 29, 0},
+}
+
+// supports reports whether git version v has the feature f.
+func (v Version) supports(f feature) bool {
+	fv := featureVersions[f]
+	return v.AtLeast(fv.Major, fv.Minor, fv.Patch)
+}
+
+// ErrGitTooOld is returned when the version of the git executable is older
+// than the oldest version supported by this package.
+var ErrGitTooOld = errors.New("git version too old")
+
+var (
+	gitVersionMu  sync.Mutex
+	gitVersion    *Version
+	gitVersionErr error // set if gitVersion is older than minVersion
+)
+
+// GitVersion returns the version of the git executable used by this package.
+//
+// The version is detected on first use, then cached. If it's older than the
+// oldest version supported by this package, GitVersion returns it along with
+// ErrGitTooOld.
+func GitVersion() (Version, error) { return detectGitVersion(context.Background(), &options{}) }
+
+// GitVersionWithContext is like GitVersion but includes a context.
+func GitVersionWithContext(ctx context.Context) (Version, error) {
+	return detectGitVersion(ctx, &options{})
+}
+
+func detectGitVersion(ctx context.Context, o *options) (Version, error) {
+	if o.replay != nil {
+		// The recorded version is never cached, nor is it consumed, since it's
+		// looked up every time the version is needed.
+		if cmd, ok := o.replay.lookup("git", "version"); ok {
+			var v Version
+			if err := v.parseFrom(strings.NewReader(cmd.Stdout)); err != nil {
+				return Version{}, withCode(CodeParseError, err)
+			}
+			return v, checkVersion(v)
+		}
+		// Assume the version of the local git executable.
+		o = &options{}
+	}
+
+	gitVersionMu.Lock()
+	defer gitVersionMu.Unlock()
+
+	if gitVersion != nil {
+		if o.capture != nil {
+			// Record the cached version, so that the capture can be replayed
+			// with the same arguments.
+			o.capture.add(CapturedCommand{
+				Args:   []string{"git", "version"},
+				Stdout: "git version " + gitVersion.String() + "\n",
+			})
+		}
+		return *gitVersion, gitVersionErr
+	}
+
+	var v Version
+	if err := o.runAndParse(ctx, &v, "git", "version"); err != nil {
+		return Version{}, err
+	}
+
+	// Cache unsupported versions too, so as not to detect them again.
+	gitVersion, gitVersionErr = &v, checkVersion(v)
+	return v, gitVersionErr
+}
+
+// checkVersion returns an error if v is older than minVersion.
+func checkVersion(v Version) error {
+	if !v.AtLeast(minVersion.Major, minVersion.Minor, minVersion.Patch) {
+		return withCode(CodeGitTooOld, fmt.Errorf("%w: found %v, at least %v is required", ErrGitTooOld, v, minVersion))
+	}
+	return nil
+}
+
+// parseFrom parses the output of git version, for example:
+//
+//	git version 2.39.2
+//	git version 2.39.2 (Apple Git-143)
+//	git version 2.41.0.windows.1
+func (v *Version) parseFrom(r io.Reader) error {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	const prefix = "git version "
+	s := strings.TrimSpace(string(b))
+	if !strings.HasPrefix(s, prefix) {
+		return fmt.Errorf("unexpected git version output %q", s)
+	}
+	s = s[len(prefix):]
+
+	// Only keep major, minor and optional patch numbers.
+	var nums [3]int
+	for i, f := range strings.SplitN(s, ".", 4) {
+		if i == len(nums) {
+			break
+		}
+		n := 0
+		j := 0
+		for ; j < len(f) && '0' <= f[j] && f[j] <= '9'; j++ {
+			n = n*10 + int(f[j]-'0')
+		}
+		if j == 0 {
+			if i < 2 {
+				return fmt.Errorf("unexpected git version %q", s)
+			}
+			break
+		}
+		nums[i] = n
+		if j != len(f) {
+			// Trailing non-numeric characters, as in 2.0.0-rc1.
+			break
+		}
+	}
+
+	*v = Version{Major: nums[0], Minor: nums[1], Patch: nums[2]}
+	return nil
+}
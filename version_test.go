@@ -0,0 +1,88 @@
+package gitstatus
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVersionParse(t *testing.T) {
+	tests := []struct {
+		out     string // git version output
+		want    Version
+		wantErr bool
+	}{
+		{out: "git version 2.39.2\n", want: Version{2, 39, 2}},
+		{out: "git version 2.39.2 (Apple Git-143)\n", want: Version{2, 39, 2}},
+		{out: "git version 2.41.0.windows.1\n", want: Version{2, 41, 0}},
+		{out: "git version 1.8.3.1\n", want: Version{1, 8, 3}},
+		{out: "git version 2.40.0-rc1\n", want: Version{2, 40, 0}},
+		{out: "git version 2.4\n", want: Version{2, 4, 0}},
+		{out: "git version\n", wantErr: true},
+		{out: "git version x.y.z\n", wantErr: true},
+		{out: "version 2.39.2\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.out, func(t *testing.T) {
+			var got Version
+			err := got.parseFrom(strings.NewReader(tt.out))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestVersionAtLeast(t *testing.T) {
+	v := Version{2, 11, 3}
+
+	assert.True(t, v.AtLeast(2, 11, 3))
+	assert.True(t, v.AtLeast(2, 11, 0))
+	assert.True(t, v.AtLeast(2, 9, 9))
+	assert.True(t, v.AtLeast(1, 20, 0))
+	assert.False(t, v.AtLeast(2, 11, 4))
+	assert.False(t, v.AtLeast(2, 12, 0))
+	assert.False(t, v.AtLeast(3, 0, 0))
+}
+
+func TestVersionSupports(t *testing.T) {
+	// All features appeared after the oldest supported version.
+	for f, fv := range featureVersions {
+		assert.Falsef(t, minVersion.supports(feature(f)), "feature %d", f)
+		assert.Truef(t, fv.supports(feature(f)), "feature %d", f)
+	}
+
+	v := Version{2, 13, 0}
+	assert.True(t, v.supports(featPorcelainV1))
+	assert.True(t, v.supports(featAbsoluteGitDir))
+	assert.False(t, v.supports(featNoAheadBehind))
+}
+
+func TestGitTooOldCached(t *testing.T) {
+	gitVersionMu.Lock()
+	saved, savedErr := gitVersion, gitVersionErr
+	gitVersion, gitVersionErr = &Version{1, 7, 1}, checkVersion(Version{1, 7, 1})
+	gitVersionMu.Unlock()
+	defer func() {
+		gitVersionMu.Lock()
+		gitVersion, gitVersionErr = saved, savedErr
+		gitVersionMu.Unlock()
+	}()
+
+	// The cached result is returned, git is not run again: the capture only
+	// holds the cached version.
+	var dc DebugCapture
+	v, err := detectGitVersion(context.Background(), &options{capture: &dc})
+	assert.Equal(t, Version{1, 7, 1}, v)
+	assert.Truef(t, errors.Is(err, ErrGitTooOld), "got error %v, want %v", err, ErrGitTooOld)
+	assert.Equal(t, []CapturedCommand{
+		{Args: []string{"git", "version"}, Stdout: "git version 1.7.1\n"},
+	}, dc.Commands())
+}
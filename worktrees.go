@@ -0,0 +1,130 @@
+package gitstatus
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Worktree is a working tree of a repository, as listed by git worktree list.
+type Worktree struct {
+	// Path is the absolute path of the working tree.
+	Path string
+
+	// Status is the git status of the working tree, nil if it couldn't be
+	// retrieved.
+	Status *Status
+
+	// Err is the error that occurred retrieving Status, if any.
+	Err error
+}
+
+// Worktrees returns the Status of each working tree of the repository of the
+// current working directory, starting with the main working tree. opts are
+// applied to the computation of every Status.
+//
+// Bare repositories and working trees whose directory has been deleted
+// (prunable) are omitted. Failing to retrieve the status of a working tree
+// doesn't prevent that of the others from being retrieved: its error is
+// reported in the Err field of the Worktree.
+func Worktrees(opts ...Option) ([]Worktree, error) {
+	return listWorktrees(context.Background(), opts)
+}
+
+// WorktreesWithContext is like Worktrees but includes a context.
+//
+// The provided context is used to stop retrieving the status of the worktrees
+// if the context becomes done before all calls to git have completed.
+func WorktreesWithContext(ctx context.Context, opts ...Option) ([]Worktree, error) {
+	return listWorktrees(ctx, opts)
+}
+
+func listWorktrees(ctx context.Context, opts []Option) ([]Worktree, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if err := o.checkRepository(); err != nil {
+		return nil, err
+	}
+
+	ver, err := detectGitVersion(ctx, &o)
+	if err != nil {
+		return nil, err
+	}
+	if !ver.supports(featWorktreeList) {
+		return nil, withCode(CodeGitTooOld, fmt.Errorf("%w: git worktree list requires git %v, found %v",
+			ErrGitTooOld, featureVersions[featWorktreeList], ver))
+	}
+
+	var wl worktreeList
+	if err := o.runAndParse(ctx, &wl, "git", "worktree", "list", "--porcelain"); err != nil {
+		return nil, err
+	}
+
+	worktrees := make([]Worktree, 0, len(wl))
+	for _, path := range wl {
+		// Options are applied in order, so WithDir overrides any other.
+		st, err := newStatus(ctx, append(opts[:len(opts):len(opts)], WithDir(path)))
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctxErr(ctx)
+			}
+			worktrees = append(worktrees, Worktree{Path: path, Err: err})
+			continue
+		}
+		worktrees = append(worktrees, Worktree{Path: path, Status: st})
+	}
+	return worktrees, nil
+}
+
+// worktreeList is the list of paths of the working trees to report.
+type worktreeList []string
+
+// parseFrom parses the output of git worktree list --porcelain, made of
+// records separated by empty lines, for example:
+//
+//	worktree /path/to/main
+//	HEAD 2f4ce1b7ed9d6e1d3e3b7f1a0b5d0ee4c5f0c1a2
+//	branch refs/heads/main
+//
+//	worktree /path/to/fix
+//	HEAD 2f4ce1b7ed9d6e1d3e3b7f1a0b5d0ee4c5f0c1a2
+//	detached
+//	prunable gitdir file points to non-existent location
+func (wl *worktreeList) parseFrom(r io.Reader) error {
+	scan, release := newScanner(r, bufio.ScanLines)
+	defer release()
+
+	var (
+		path string
+		skip bool
+	)
+	flush := func() {
+		if path != "" && !skip {
+			*wl = append(*wl, path)
+		}
+		path, skip = "", false
+	}
+
+	for scan.Scan() {
+		line := scan.Text()
+		attr, _, _ := strings.Cut(line, " ")
+		switch attr {
+		case "":
+			flush()
+		case "worktree":
+			if path != "" {
+				return fmt.Errorf("unexpected worktree line %q", line)
+			}
+			path = strings.TrimPrefix(line, "worktree ")
+		case "bare", "prunable":
+			skip = true
+		}
+	}
+	flush()
+
+	return scan.Err()
+}
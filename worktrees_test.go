@@ -0,0 +1,112 @@
+package gitstatus
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorktreeListParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		out     string // git worktree list --porcelain output
+		want    worktreeList
+		wantErr bool
+	}{
+		{
+			name: "main only",
+			out:  "worktree /src/repo\nHEAD 1234\nbranch refs/heads/main\n\n",
+			want: worktreeList{"/src/repo"},
+		},
+		{
+			name: "all cases",
+			out: "worktree /src/bare.git\nbare\n\n" +
+				"worktree /src/with space\nHEAD 1234\nbranch refs/heads/fix\n\n" +
+				"worktree /src/detached\nHEAD 1234\ndetached\nlocked\n\n" +
+				"worktree /src/gone\nHEAD 1234\ndetached\nprunable gitdir file points to non-existent location\n\n",
+			want: worktreeList{"/src/with space", "/src/detached"},
+		},
+		{
+			name: "no trailing empty line",
+			out:  "worktree /src/repo\nHEAD 1234\nbranch refs/heads/main",
+			want: worktreeList{"/src/repo"},
+		},
+		{
+			name:    "missing separator",
+			out:     "worktree /src/repo\nworktree /src/other\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got worktreeList
+			err := got.parseFrom(strings.NewReader(tt.out))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestWorktrees(t *testing.T) {
+	dir := t.TempDir()
+	main := filepath.Join(dir, "main")
+	fix := filepath.Join(dir, "fix")
+	require.NoError(t, os.Mkdir(main, 0755))
+
+	runGit(t, main, "init", "-q")
+	runGit(t, main, "checkout", "-q", "-b", "main")
+	runGit(t, main, "commit", "-q", "--allow-empty", "-m", "initial commit")
+	runGit(t, main, "worktree", "add", "-q", "-b", "fix", fix)
+	require.NoError(t, os.WriteFile(filepath.Join(fix, "file"), []byte("fix\n"), 0644))
+
+	// Worktrees can be listed from any of them.
+	chdir(t, fix)
+	wts, err := Worktrees()
+	require.NoError(t, err)
+	require.Len(t, wts, 2)
+
+	mainPath, err := filepath.EvalSymlinks(main)
+	require.NoError(t, err)
+	fixPath, err := filepath.EvalSymlinks(fix)
+	require.NoError(t, err)
+
+	assert.Equal(t, mainPath, filepath.FromSlash(wts[0].Path))
+	assert.Equal(t, "main", wts[0].Status.LocalBranch)
+	assert.True(t, wts[0].Status.IsClean)
+
+	assert.Equal(t, fixPath, filepath.FromSlash(wts[1].Path))
+	assert.Equal(t, "fix", wts[1].Status.LocalBranch)
+	assert.Equal(t, 1, wts[1].Status.NumUntracked)
+	assert.Equal(t, wts[0].Status.HEADFull, wts[1].Status.HEADFull)
+
+	// A working tree whose status can't be retrieved doesn't prevent the
+	// others from being reported.
+	index := filepath.Join(main, ".git", "worktrees", "fix", "index")
+	require.NoError(t, os.WriteFile(index, []byte("corrupt"), 0644))
+
+	wts, err = Worktrees()
+	require.NoError(t, err)
+	require.Len(t, wts, 2)
+
+	assert.NoError(t, wts[0].Err)
+	assert.Equal(t, "main", wts[0].Status.LocalBranch)
+
+	assert.Equal(t, fixPath, filepath.FromSlash(wts[1].Path))
+	assert.Error(t, wts[1].Err)
+	assert.Nil(t, wts[1].Status)
+
+	// Outside of a repository.
+	notRepo := t.TempDir()
+	_, err = Worktrees(WithDir(notRepo), WithCeilingDirectories(filepath.Dir(notRepo)))
+	assert.Truef(t, errors.Is(err, ErrNotARepository), "got error %v, want %v", err, ErrNotARepository)
+}